@@ -0,0 +1,19 @@
+package main
+
+import "syscall"
+
+// MNT_NOATIME from <sys/mount.h>
+const MNT_NOATIME = 0x10000000
+
+// atimeReliability tells whether access times on the volume holding path
+// can be trusted
+func atimeReliability(path string) (bool, string) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return true, ""
+	}
+	if fs.Flags&MNT_NOATIME != 0 {
+		return false, int8ToString(fs.Mntonname[:]) + " is mounted noatime, access times are never updated"
+	}
+	return true, ""
+}
@@ -0,0 +1,21 @@
+package main
+
+// atimeReliability tells whether access times on the filesystem holding
+// path can be trusted. noatime never updates them, relatime (the kernel
+// default) only when atime is older than mtime/ctime or older than a day.
+func atimeReliability(path string) (bool, string) {
+	m, ok := findMount(path)
+	if !ok {
+		return true, ""
+	}
+
+	for _, opt := range m.Options {
+		switch opt {
+		case "noatime":
+			return false, m.MountPoint + " is mounted noatime, access times are never updated"
+		case "relatime":
+			return false, m.MountPoint + " is mounted relatime, access times are updated at most once a day"
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,8 @@
+//go:build !windows && !linux && !darwin
+
+package main
+
+// No way to check mount options here, assume access times are kept
+func atimeReliability(path string) (bool, string) {
+	return true, ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// atimeReliability tells whether NTFS last-access times can be trusted.
+// Windows turns last-access updates off through the registry value
+// HKLM\SYSTEM\CurrentControlSet\Control\FileSystem\NtfsDisableLastAccessUpdate;
+// since Windows 10 1803 it is 0x8000000X, where an odd value means disabled.
+func atimeReliability(path string) (bool, string) {
+	keyPath, err := syscall.UTF16PtrFromString(`SYSTEM\CurrentControlSet\Control\FileSystem`)
+	if err != nil {
+		return true, ""
+	}
+	valueName, err := syscall.UTF16PtrFromString("NtfsDisableLastAccessUpdate")
+	if err != nil {
+		return true, ""
+	}
+
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, keyPath, 0, syscall.KEY_READ, &key); err != nil {
+		return true, ""
+	}
+	defer syscall.RegCloseKey(key)
+
+	var value, valueType uint32
+	size := uint32(unsafe.Sizeof(value))
+	if err := syscall.RegQueryValueEx(key, valueName, nil, &valueType, (*byte)(unsafe.Pointer(&value)), &size); err != nil {
+		return true, ""
+	}
+	if valueType != syscall.REG_DWORD {
+		return true, ""
+	}
+
+	if value&1 != 0 {
+		return false, "NTFS last-access updates are disabled (NtfsDisableLastAccessUpdate), access times are stale"
+	}
+	return true, ""
+}
@@ -0,0 +1,750 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// browserNode is a file or directory in the --browse tree. Directories
+// exist only as the parents of matched files.
+type browserNode struct {
+	name     string
+	path     string
+	info     *FileInfo // nil for directories
+	findings []RuleFinding
+	parent   *browserNode
+	children []*browserNode
+	expanded bool
+	depth    int
+
+	// worked out for the current filter by refresh
+	visible bool
+	size    int64 // the file, or every visible file below the directory
+	files   int
+	flagged int
+}
+
+func (n *browserNode) isDir() bool { return n.info == nil }
+
+// browserFilter narrows the tree to some files, directories show when a
+// file below them does
+type browserFilter struct {
+	label string
+	match func(n *browserNode) bool
+}
+
+// browser is the state of the --browse screen
+type browser struct {
+	rules []Rule
+	roots []*browserNode
+
+	filters []browserFilter
+	filter  int
+
+	rows     []*browserNode // the visible tree, top to bottom
+	cursor   int
+	offset   int // first row on screen
+	selected map[*browserNode]bool
+
+	width, height int
+	status        string
+	confirming    bool // the status line asks whether to trash the selection
+
+	history   *DeletionHistory // loaded on the first trash action
+	sessionID string
+	trashed   int
+	freed     int64
+}
+
+// newBrowser builds the tree of the matched files under their scan roots
+// and evaluates the rules for each of them once
+func newBrowser(files []*FileInfo, rules []Rule) *browser {
+	b := &browser{rules: rules, selected: map[*browserNode]bool{}}
+
+	rootsByPath := map[string]*browserNode{}
+	for _, info := range files {
+		rel := info.RelPath
+		if rel == "" {
+			rel = getFileName(info.Path)
+		}
+		parts := strings.Split(rel, "/")
+
+		// ancestors[0] is the file's directory, the last one the scan root
+		ancestors := make([]string, len(parts))
+		p := info.Path
+		for i := range ancestors {
+			p = parentPath(p)
+			ancestors[i] = p
+		}
+
+		rootPath := ancestors[len(ancestors)-1]
+		node, ok := rootsByPath[rootPath]
+		if !ok {
+			node = &browserNode{name: rootPath, path: rootPath, expanded: true}
+			rootsByPath[rootPath] = node
+			b.roots = append(b.roots, node)
+		}
+		for i, name := range parts[:len(parts)-1] {
+			node = node.child(name, ancestors[len(parts)-2-i])
+		}
+		node.children = append(node.children, &browserNode{
+			name:     parts[len(parts)-1],
+			path:     info.Path,
+			info:     info,
+			findings: EvaluateRules(rules, info),
+			parent:   node,
+			depth:    node.depth + 1,
+		})
+	}
+
+	b.filters = browserFilters(rules, files)
+	b.refresh()
+	return b
+}
+
+// child finds or adds the subdirectory name
+func (n *browserNode) child(name, path string) *browserNode {
+	for _, c := range n.children {
+		if c.isDir() && c.name == name {
+			return c
+		}
+	}
+	c := &browserNode{name: name, path: path, parent: n, depth: n.depth + 1}
+	n.children = append(n.children, c)
+	return c
+}
+
+// browserFilters are the views f cycles through: everything, every flagged
+// file, each active rule, then each file type that occurs
+func browserFilters(rules []Rule, files []*FileInfo) []browserFilter {
+	filters := []browserFilter{
+		{"All files", func(n *browserNode) bool { return true }},
+		{"Flagged", func(n *browserNode) bool { return len(n.findings) > 0 }},
+	}
+	for _, rule := range rules {
+		id := rule.ID()
+		filters = append(filters, browserFilter{"Rule: " + ruleTitle(id), func(n *browserNode) bool {
+			return hasFinding(n.findings, id)
+		}})
+	}
+
+	present := map[*FileCategory]bool{}
+	for _, info := range files {
+		if cat := typeCatalog.ClassifyFile(info); cat != nil {
+			present[cat] = true
+		}
+	}
+	for i := range typeCatalog.Categories {
+		cat := &typeCatalog.Categories[i]
+		if !present[cat] {
+			continue
+		}
+		filters = append(filters, browserFilter{"Type: " + cat.DisplayName(), func(n *browserNode) bool {
+			return typeCatalog.ClassifyFile(n.info) == cat
+		}})
+	}
+	return filters
+}
+
+// refresh recomputes what the filter shows and rebuilds the rows, keeping
+// the cursor on the same node when it's still visible
+func (b *browser) refresh() {
+	var current *browserNode
+	if b.cursor < len(b.rows) {
+		current = b.rows[b.cursor]
+	}
+
+	match := b.filters[b.filter].match
+	for _, root := range b.roots {
+		b.tally(root, match)
+	}
+
+	b.rows = b.rows[:0]
+	for _, root := range b.roots {
+		b.addRows(root)
+	}
+
+	b.cursor = 0
+	for i, n := range b.rows {
+		if n == current {
+			b.cursor = i
+			break
+		}
+	}
+}
+
+// tally works out visible, size, files and flagged below n
+func (b *browser) tally(n *browserNode, match func(*browserNode) bool) {
+	if !n.isDir() {
+		n.visible = match(n)
+		n.size, n.files, n.flagged = 0, 0, 0
+		if n.visible {
+			n.size, n.files = n.info.SizeBytes, 1
+			if len(n.findings) > 0 {
+				n.flagged = 1
+			}
+		}
+		return
+	}
+
+	n.size, n.files, n.flagged = 0, 0, 0
+	for _, c := range n.children {
+		b.tally(c, match)
+		n.size += c.size
+		n.files += c.files
+		n.flagged += c.flagged
+	}
+	n.visible = n.files > 0
+
+	// largest first, like --usage
+	sort.SliceStable(n.children, func(i, j int) bool {
+		if n.children[i].size != n.children[j].size {
+			return n.children[i].size > n.children[j].size
+		}
+		return n.children[i].name < n.children[j].name
+	})
+}
+
+func (b *browser) addRows(n *browserNode) {
+	if !n.visible {
+		return
+	}
+	b.rows = append(b.rows, n)
+	if n.isDir() && n.expanded {
+		for _, c := range n.children {
+			b.addRows(c)
+		}
+	}
+}
+
+// visibleFiles lists the files below n (or n itself) the filter shows
+func visibleFiles(n *browserNode) []*browserNode {
+	if !n.visible {
+		return nil
+	}
+	if !n.isDir() {
+		return []*browserNode{n}
+	}
+	var files []*browserNode
+	for _, c := range n.children {
+		files = append(files, visibleFiles(c)...)
+	}
+	return files
+}
+
+// selection is what a trash action works on: the selected files, or the
+// file under the cursor when nothing is selected
+func (b *browser) selection() []*browserNode {
+	var nodes []*browserNode
+	for n := range b.selected {
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 0 && b.cursor < len(b.rows) && !b.rows[b.cursor].isDir() {
+		nodes = append(nodes, b.rows[b.cursor])
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].path < nodes[j].path })
+	return nodes
+}
+
+func totalSize(nodes []*browserNode) int64 {
+	var size int64
+	for _, n := range nodes {
+		size += n.info.SizeBytes
+	}
+	return size
+}
+
+// handleKey acts on one key and reports whether the browser should close
+func (b *browser) handleKey(key string) bool {
+	if b.confirming {
+		b.confirming = false
+		if key == "y" || key == "Y" {
+			b.trash()
+		} else {
+			b.status = "Cancelled"
+		}
+		return false
+	}
+	b.status = ""
+
+	page := b.treeHeight() - 1
+	if page < 1 {
+		page = 1
+	}
+
+	switch key {
+	case "q", "esc", "ctrl-c":
+		return true
+	case "up", "k":
+		b.move(-1)
+	case "down", "j":
+		b.move(1)
+	case "pgup":
+		b.move(-page)
+	case "pgdn":
+		b.move(page)
+	case "home", "g":
+		b.move(-len(b.rows))
+	case "end", "G":
+		b.move(len(b.rows))
+	case "right", "l", "enter":
+		if n := b.current(); n != nil && n.isDir() {
+			n.expanded = true
+			b.refresh()
+		}
+	case "left", "h":
+		n := b.current()
+		switch {
+		case n == nil:
+		case n.isDir() && n.expanded:
+			n.expanded = false
+			b.refresh()
+		case n.parent != nil:
+			b.moveTo(n.parent)
+		}
+	case "space":
+		b.toggle()
+	case "f":
+		b.filter = (b.filter + 1) % len(b.filters)
+		b.refresh()
+	case "F":
+		b.filter = (b.filter + len(b.filters) - 1) % len(b.filters)
+		b.refresh()
+	case "a":
+		for n := range b.selected {
+			delete(b.selected, n)
+		}
+	case "t", "d":
+		nodes := b.selection()
+		if len(nodes) == 0 {
+			b.status = "Nothing selected (space selects files)"
+			break
+		}
+		b.confirming = true
+		b.status = fmt.Sprintf("Move %d files (%s) to the %s? (y/N)", len(nodes), formatFileSize(totalSize(nodes)), trashBackend.Name())
+	}
+	return false
+}
+
+func (b *browser) current() *browserNode {
+	if b.cursor < len(b.rows) {
+		return b.rows[b.cursor]
+	}
+	return nil
+}
+
+func (b *browser) move(delta int) {
+	b.cursor += delta
+	if b.cursor >= len(b.rows) {
+		b.cursor = len(b.rows) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+func (b *browser) moveTo(n *browserNode) {
+	for i, row := range b.rows {
+		if row == n {
+			b.cursor = i
+			return
+		}
+	}
+}
+
+// toggle selects the file under the cursor, or every visible file in the
+// directory under it (deselects them when they all are selected already)
+func (b *browser) toggle() {
+	n := b.current()
+	if n == nil {
+		return
+	}
+	files := visibleFiles(n)
+	all := true
+	for _, f := range files {
+		if !b.selected[f] {
+			all = false
+		}
+	}
+	for _, f := range files {
+		if all {
+			delete(b.selected, f)
+		} else {
+			b.selected[f] = true
+		}
+	}
+	b.move(1)
+}
+
+// trash moves the selection to the trash in one session, like --delete
+// does, and takes what was moved out of the tree
+func (b *browser) trash() {
+	if b.history == nil {
+		history, err := LoadHistory(GetHistoryFilePath())
+		if err != nil {
+			b.status = "Failed to load history: " + err.Error()
+			return
+		}
+		b.history = history
+		b.sessionID = newSessionID()
+	}
+
+	moved := 0
+	var freed int64
+	var failures []string
+	for _, n := range b.selection() {
+		if err := DeleteFile(*n.info, b.history, b.sessionID); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", n.name, err))
+			continue
+		}
+		moved++
+		freed += n.info.SizeBytes
+		delete(b.selected, n)
+		n.remove()
+	}
+	b.trashed += moved
+	b.freed += freed
+
+	b.status = fmt.Sprintf("Moved %d files (%s) to the %s", moved, formatFileSize(freed), trashBackend.Name())
+	if moved > 0 {
+		if err := SaveHistory(b.history, GetHistoryFilePath()); err != nil {
+			b.status += ", but failed to save history: " + err.Error()
+		}
+	}
+	if len(failures) > 0 {
+		b.status += fmt.Sprintf("; %d failed, e.g. %s", len(failures), failures[0])
+	}
+	b.refresh()
+}
+
+// remove takes n out of its parent, and directories left empty with it
+func (n *browserNode) remove() {
+	for parent := n.parent; parent != nil; n, parent = parent, parent.parent {
+		for i, c := range parent.children {
+			if c == n {
+				parent.children = append(parent.children[:i], parent.children[i+1:]...)
+				break
+			}
+		}
+		if len(parent.children) > 0 || parent.parent == nil {
+			return
+		}
+	}
+}
+
+// treeHeight is how many rows of the tree fit on screen
+func (b *browser) treeHeight() int {
+	h := b.height - 3 // header, status and key help
+	if !b.sidePane() {
+		h -= h * 2 / 5 // details below the tree
+	}
+	return h
+}
+
+// sidePane reports whether the details go right of the tree rather than
+// under it
+func (b *browser) sidePane() bool {
+	return b.width >= 100
+}
+
+// render draws the whole screen
+func (b *browser) render() string {
+	if b.width < 20 || b.height < 8 {
+		return "\x1b[H\x1b[2JTerminal too small"
+	}
+
+	// keep the cursor on screen
+	th := b.treeHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+th {
+		b.offset = b.cursor - th + 1
+	}
+
+	var selSize int64
+	for n := range b.selected {
+		selSize += n.info.SizeBytes
+	}
+	header := fmt.Sprintf(" Filesystem Analyzer │ %s │ %d selected (%s)",
+		b.filters[b.filter].label, len(b.selected), formatFileSize(selSize))
+
+	treeWidth := b.width
+	detailWidth := b.width
+	if b.sidePane() {
+		treeWidth = b.width * 55 / 100
+		detailWidth = b.width - treeWidth - 1
+	}
+
+	tree := make([]string, th)
+	for i := range tree {
+		row := b.offset + i
+		if row >= len(b.rows) {
+			tree[i] = fit("", treeWidth)
+			continue
+		}
+		line := fit(b.rowText(b.rows[row]), treeWidth)
+		switch {
+		case row == b.cursor:
+			line = "\x1b[7m" + line + ColorReset + "\x1b[27m"
+		case b.selected[b.rows[row]]:
+			line = ColorGreen + line + ColorReset
+		case len(b.rows[row].findings) > 0:
+			line = severityColor(b.rows[row].findings[0].Rule.Severity()) + line + ColorReset
+		}
+		tree[i] = line
+	}
+
+	var lines []string
+	lines = append(lines, "\x1b[7m"+fit(header, b.width)+"\x1b[27m")
+	if b.sidePane() {
+		details := b.details(detailWidth, th)
+		for i := range tree {
+			lines = append(lines, tree[i]+ColorDim+"│"+ColorReset+fit(details[i], detailWidth))
+		}
+	} else {
+		lines = append(lines, tree...)
+		dh := b.height - 3 - th
+		lines = append(lines, ColorDim+strings.Repeat("─", b.width)+ColorReset)
+		for _, d := range b.details(b.width, dh-1) {
+			lines = append(lines, fit(d, b.width))
+		}
+	}
+
+	status := b.status
+	if b.confirming {
+		status = ColorYellow + ColorBold + fit(status, b.width) + ColorReset
+	} else {
+		status = fit(status, b.width)
+	}
+	lines = append(lines, status)
+	lines = append(lines, ColorDim+fit(" ↑↓ move  ←→ collapse/expand  space select  a clear  f/F filter  t trash  q quit", b.width)+ColorReset)
+
+	return "\x1b[H" + strings.Join(lines, "\x1b[K\r\n") + "\x1b[K"
+}
+
+// rowText is one line of the tree: indentation, marker, name and size
+func (b *browser) rowText(n *browserNode) string {
+	marker := "  "
+	switch {
+	case n.isDir() && n.expanded:
+		marker = "▾ "
+	case n.isDir():
+		marker = "▸ "
+	case b.selected[n]:
+		marker = "● "
+	}
+	name := n.name
+	if n.isDir() && n.parent != nil {
+		name += "/"
+	}
+	flag := ""
+	if len(n.findings) > 0 {
+		flag = " ⚠"
+	}
+	return fmt.Sprintf("%9s  %s%s%s%s", formatFileSize(n.size), strings.Repeat("  ", n.depth), marker, name, flag)
+}
+
+// details describes the node under the cursor in at most height lines
+func (b *browser) details(width, height int) []string {
+	var lines []string
+	n := b.current()
+	switch {
+	case n == nil:
+		lines = append(lines, " Nothing matches this filter")
+	case n.isDir():
+		lines = append(lines,
+			" "+n.path,
+			"",
+			" Size:    "+formatFileSize(n.size),
+			fmt.Sprintf(" Files:   %d", n.files),
+			fmt.Sprintf(" Flagged: %d", n.flagged))
+	default:
+		info := n.info
+		lines = append(lines,
+			" "+info.Path,
+			"",
+			" Size:     "+formatFileSize(info.SizeBytes),
+			" Type:     "+getFileType(info),
+			" Modified: "+info.ModifiedAt.Format("2006-01-02 15:04"))
+		if !info.ListedVerbatim() {
+			lines = append(lines, " Path corrected, can't be trashed: "+info.PathCorrection)
+		}
+		if len(n.findings) == 0 {
+			lines = append(lines, "", " No findings")
+		}
+		for _, f := range n.findings {
+			lines = append(lines, "", " ⚠ "+ruleTitle(f.Rule.ID())+": "+f.Explanation.Reason)
+			for _, e := range f.Explanation.Evidence {
+				lines = append(lines, wrapText("   ▸ "+e, "     ", width)...)
+			}
+		}
+	}
+
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines[:height]
+}
+
+// fit truncates or pads s to exactly width columns (counting runes)
+func fit(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	if width < 1 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// wrapText breaks s into lines of at most width runes, continuation lines
+// start with indent
+func wrapText(s, indent string, width int) []string {
+	var lines []string
+	runes := []rune(s)
+	prefix := utf8.RuneCountInString(indent)
+	for len(runes) > width && width > prefix+1 {
+		cut := width
+		for i := width; i > prefix; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, string(runes[:cut]))
+		runes = append([]rune(indent), []rune(strings.TrimLeft(string(runes[cut:]), " "))...)
+	}
+	return append(lines, string(runes))
+}
+
+// readKeys turns terminal input into key names ("up", "space", "q", ...)
+// until stdin closes
+func readKeys(in *os.File, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	var pending []byte // a sequence the last read cut off
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		parsed, rest := parseKeys(append(pending, buf[:n]...))
+		for _, key := range parsed {
+			keys <- key
+		}
+		// no key sends that much, it's noise
+		if len(rest) > 16 {
+			rest = nil
+		}
+		pending = append([]byte(nil), rest...)
+	}
+}
+
+// escapeKeys are the sequences arrow and paging keys send, after ESC [ or
+// ESC O
+var escapeKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left",
+	"H": "home", "F": "end", "1~": "home", "4~": "end", "7~": "home", "8~": "end",
+	"5~": "pgup", "6~": "pgdn",
+}
+
+// parseKeys turns raw input into key names. An escape sequence or UTF-8
+// character cut off at the end of buf comes back as rest, to be put in
+// front of the next read.
+func parseKeys(buf []byte) (keys []string, rest []byte) {
+	for len(buf) > 0 {
+		c := buf[0]
+		switch {
+		case c == 0x1b && len(buf) >= 2 && (buf[1] == '[' || buf[1] == 'O'):
+			// the sequence ends at its first letter or ~
+			end := 2
+			for end < len(buf) && !(buf[end] >= 'A' && buf[end] <= 'Z' || buf[end] == '~') {
+				end++
+			}
+			if end == len(buf) {
+				return keys, buf
+			}
+			if key, ok := escapeKeys[string(buf[2:end+1])]; ok {
+				keys = append(keys, key)
+			}
+			buf = buf[end+1:]
+			continue
+		case c == 0x1b:
+			keys = append(keys, "esc")
+		case c == 0x03:
+			keys = append(keys, "ctrl-c")
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+		case c == ' ':
+			keys = append(keys, "space")
+		case !utf8.FullRune(buf):
+			return keys, buf
+		default:
+			r, size := utf8.DecodeRune(buf)
+			keys = append(keys, string(r))
+			buf = buf[size:]
+			continue
+		}
+		buf = buf[1:]
+	}
+	return keys, nil
+}
+
+// Run shows the browser until the user quits. The terminal is in raw mode
+// and on the alternate screen meanwhile, and restored whatever happens.
+func (b *browser) Run(in, out *os.File) error {
+	restore, err := makeRaw(in, out)
+	if err != nil {
+		return fmt.Errorf("cannot switch the terminal to raw mode: %v", err)
+	}
+	defer restore()
+
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	if b.width, b.height, err = terminalSize(out); err != nil {
+		b.width, b.height = 80, 24
+	}
+
+	keys := make(chan string, 16)
+	go readKeys(in, keys)
+	resized := make(chan struct{}, 1)
+	stop := notifyResize(out, resized)
+	defer stop()
+
+	for {
+		fmt.Fprint(out, b.render())
+		select {
+		case key, ok := <-keys:
+			if !ok || b.handleKey(key) {
+				return nil
+			}
+		case <-resized:
+			if w, h, err := terminalSize(out); err == nil {
+				b.width, b.height = w, h
+			}
+			fmt.Fprint(out, "\x1b[2J")
+		}
+	}
+}
+
+// handleBrowseMode opens the browser on the matched files
+func handleBrowseMode(files []*FileInfo, rules []Rule) int {
+	b := newBrowser(files, rules)
+	if err := b.Run(os.Stdin, os.Stdout); err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+
+	if b.trashed > 0 {
+		PrintSuccess(fmt.Sprintf("%d files (%s) moved to the %s", b.trashed, formatFileSize(b.freed), trashBackend.Name()))
+		PrintInfo("Use --undo-session " + b.sessionID + " to restore them")
+	}
+	return ExitClean
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		keys string
+		rest string
+	}{
+		{"\x1b[A\x1b[B\x1b[C\x1b[D", "up down right left", ""},
+		{"\x1bOA\x1bOB", "up down", ""}, // application cursor mode
+		{"\x1b[5~\x1b[6~\x1b[H\x1b[4~", "pgup pgdn home end", ""},
+		{"jk q", "j k space q", ""},
+		{"\r\n\x03", "enter enter ctrl-c", ""},
+		{"\x1b", "esc", ""},
+		{"\x1b[1;5C", "", ""}, // ctrl-right, not bound
+		{"é", "é", ""},
+		// cut off by the end of a read
+		{"j\x1b[", "j", "\x1b["},
+		{"\x1b[5", "", "\x1b[5"},
+		{"\x1bO", "", "\x1bO"},
+		{"a\xc3", "a", "\xc3"},
+	}
+	for _, tt := range tests {
+		keys, rest := parseKeys([]byte(tt.in))
+		if strings.Join(keys, " ") != tt.keys || string(rest) != tt.rest {
+			t.Errorf("parseKeys(%q) = %q, %q; want %s, %q", tt.in, keys, rest, tt.keys, tt.rest)
+		}
+	}
+
+	// the next read completes what was cut off (a lone ESC is the Esc key)
+	for _, split := range [][2]string{{"\x1b[", "A"}, {"\x1b[5", "~j"}, {"\x1bO", "B"}, {"\xc3", "\xa9"}} {
+		_, rest := parseKeys([]byte(split[0]))
+		keys, rest := parseKeys(append(rest, split[1]...))
+		if len(keys) == 0 || keys[0] == "[" || len(rest) != 0 {
+			t.Errorf("%q + %q = %q, %q", split[0], split[1], keys, rest)
+		}
+	}
+}
+
+// testTrashDir moves files into a directory, on every platform
+type testTrashDir struct{ dir string }
+
+func (t testTrashDir) Name() string { return "Test Trash" }
+
+func (t testTrashDir) Trash(path string) (string, error) {
+	dest := filepath.Join(t.dir, filepath.Base(path))
+	return dest, os.Rename(path, dest)
+}
+
+func (t testTrashDir) Restore(trashPath, originalPath string) error {
+	return os.Rename(trashPath, originalPath)
+}
+
+// browserTree scans a small tree into a browser with the zero-byte rule
+func browserTree(t *testing.T) (*browser, string) {
+	t.Helper()
+	root := t.TempDir()
+	sizes := map[string]int{
+		"big.bin":       300,
+		"docs/a.txt":    100,
+		"docs/empty":    0,
+		"docs/sub/b.md": 50,
+		"docs/sub/none": 0,
+	}
+	var files []*FileInfo
+	for _, rel := range []string{"big.bin", "docs/a.txt", "docs/empty", "docs/sub/b.md", "docs/sub/none"} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, sizes[rel]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, &FileInfo{Path: path, ListedPath: path, RelPath: rel, SizeBytes: int64(sizes[rel]), IsFile: true})
+	}
+	b := newBrowser(files, []Rule{&ZeroByteRule{}})
+	b.width, b.height = 120, 40
+	return b, root
+}
+
+// rowNames lists the visible rows, directories with a trailing /
+func rowNames(b *browser) string {
+	var names []string
+	for _, n := range b.rows {
+		name := n.name
+		if n.isDir() && n.parent != nil {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names[1:], " ") // without the root
+}
+
+func TestBrowserTally(t *testing.T) {
+	b, root := browserTree(t)
+	r := b.roots[0]
+	if r.path != root || r.size != 450 || r.files != 5 || r.flagged != 2 {
+		t.Errorf("root %s: %d bytes, %d files, %d flagged; want %s, 450, 5, 2", r.path, r.size, r.files, r.flagged, root)
+	}
+	// largest first, subdirectories start collapsed
+	if got := rowNames(b); got != "big.bin docs/" {
+		t.Errorf("rows = %s", got)
+	}
+
+	b.handleKey("down")
+	b.handleKey("down")
+	b.handleKey("right")
+	if got := rowNames(b); got != "big.bin docs/ a.txt sub/ empty" {
+		t.Errorf("rows with docs open = %s", got)
+	}
+	if docs := b.current(); docs.name != "docs" || docs.size != 150 || docs.files != 4 || docs.flagged != 2 {
+		t.Errorf("docs: %d bytes, %d files, %d flagged", docs.size, docs.files, docs.flagged)
+	}
+
+	// f: all files -> flagged; the cursor stays on docs
+	b.handleKey("f")
+	if got := rowNames(b); got != "docs/ empty sub/" || b.current().name != "docs" {
+		t.Errorf("flagged rows = %s, cursor on %s", got, b.current().name)
+	}
+	if r.size != 0 || r.files != 2 || r.flagged != 2 {
+		t.Errorf("flagged root: %d bytes, %d files", r.size, r.files)
+	}
+	b.handleKey("F")
+	if r.files != 5 {
+		t.Errorf("back to all files: %d files", r.files)
+	}
+}
+
+func TestBrowserSelection(t *testing.T) {
+	b, _ := browserTree(t)
+	b.handleKey("end") // docs
+
+	// space on a directory takes every visible file below it
+	b.handleKey("space")
+	if len(b.selected) != 4 {
+		t.Errorf("selected %d files, want the 4 in docs", len(b.selected))
+	}
+	b.handleKey("space") // the cursor can't move past docs, again deselects them all
+	if len(b.selected) != 0 {
+		t.Errorf("still selected: %d", len(b.selected))
+	}
+
+	// without a selection the file under the cursor is it, a directory isn't
+	b.handleKey("home")
+	b.handleKey("down")
+	if sel := b.selection(); len(sel) != 1 || sel[0].name != "big.bin" {
+		t.Errorf("selection on big.bin = %v", sel)
+	}
+	b.handleKey("down")
+	if sel := b.selection(); len(sel) != 0 {
+		t.Errorf("selection on docs = %d nodes", len(sel))
+	}
+	b.handleKey("t")
+	if b.confirming || !strings.Contains(b.status, "Nothing selected") {
+		t.Errorf("trash without a selection: %q", b.status)
+	}
+
+	b.handleKey("home")
+	b.handleKey("space")
+	b.handleKey("a")
+	if len(b.selected) != 0 {
+		t.Errorf("a didn't clear the selection")
+	}
+}
+
+func TestBrowserTrash(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	defer func(backend TrashBackend) { trashBackend = backend }(trashBackend)
+	trashBackend = testTrashDir{t.TempDir()}
+
+	b, root := browserTree(t)
+	b.handleKey("f") // flagged only
+	b.handleKey("end")
+	b.handleKey("space")
+	if len(b.selected) != 2 {
+		t.Fatalf("selected %d flagged files, want 2", len(b.selected))
+	}
+
+	b.handleKey("t")
+	if !b.confirming || !strings.Contains(b.status, "Move 2 files") {
+		t.Fatalf("no confirmation: %q", b.status)
+	}
+	b.handleKey("n")
+	if _, err := os.Stat(filepath.Join(root, "docs", "empty")); err != nil || b.status != "Cancelled" {
+		t.Fatalf("n trashed something: %v, %q", err, b.status)
+	}
+
+	b.handleKey("t")
+	b.handleKey("y")
+	if b.trashed != 2 || len(b.selected) != 0 || !strings.HasPrefix(b.status, "Moved 2 files") {
+		t.Fatalf("trashed %d, status %q", b.trashed, b.status)
+	}
+	for _, rel := range []string{"docs/empty", "docs/sub/none"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel))); !os.IsNotExist(err) {
+			t.Errorf("%s still there: %v", rel, err)
+		}
+	}
+
+	// nothing is flagged any more, and the files are out of the tree
+	if len(b.rows) != 0 {
+		t.Errorf("flagged view still has %d rows", len(b.rows))
+	}
+	b.handleKey("F")
+	b.handleKey("end")
+	b.handleKey("right")
+	if got := rowNames(b); got != "big.bin docs/ a.txt sub/" || b.roots[0].files != 3 {
+		t.Errorf("rows after trashing = %s (%d files)", got, b.roots[0].files)
+	}
+
+	history, err := LoadHistory(GetHistoryFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Records) != 2 || history.Records[0].SessionID != history.Records[1].SessionID {
+		t.Errorf("history = %+v, want 2 records in one session", history.Records)
+	}
+}
+
+func TestBrowserNodeRemove(t *testing.T) {
+	root := &browserNode{name: "/r", path: "/r"}
+	x := root.child("x", "/r/x")
+	y := x.child("y", "/r/x/y")
+	keep := &browserNode{name: "k", info: &FileInfo{}, parent: x}
+	z := &browserNode{name: "z", info: &FileInfo{}, parent: y}
+	x.children = append(x.children, keep)
+	y.children = append(y.children, z)
+
+	// y is left empty and goes too, x still has k
+	z.remove()
+	if len(y.children) != 0 || len(x.children) != 1 || x.children[0] != keep {
+		t.Errorf("after removing z: x has %d children", len(x.children))
+	}
+	// now x goes, the root stays even when empty
+	keep.remove()
+	if len(root.children) != 0 {
+		t.Errorf("root still has %d children", len(root.children))
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// SF_DATALESS is set by the File Provider framework on files whose
+// content has not been downloaded (iCloud Drive, OneDrive, Dropbox)
+const SF_DATALESS = 0x40000000
+
+func isDataless(st *syscall.Stat_t) bool {
+	return st.Flags&SF_DATALESS != 0
+}
+
+// cloudMountType returns the cloud service path is synced/mounted from,
+// or "" if it's on a regular filesystem
+func cloudMountType(path string) string {
+	path = canonicalPath(path)
+
+	// File Provider based clients all live under ~/Library/CloudStorage
+	if home, err := os.UserHomeDir(); err == nil {
+		cloudStorage := filepath.Join(home, "Library", "CloudStorage")
+		if strings.HasPrefix(path, cloudStorage+"/") {
+			rest := strings.TrimPrefix(path, cloudStorage+"/")
+			return strings.SplitN(rest, "/", 2)[0] // e.g. "OneDrive-Personal"
+		}
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return ""
+	}
+
+	fstype := strings.ToLower(int8ToString(fs.Fstypename[:]))
+	if !strings.Contains(fstype, "fuse") {
+		return ""
+	}
+	// macFUSE mounts name the driver in the mount source (e.g. "rclone@gdrive:")
+	source := int8ToString(fs.Mntfromname[:])
+	if source == "" {
+		return fstype
+	}
+	return source
+}
+
+// int8ToString converts the NUL-terminated char arrays in Statfs_t
+func int8ToString(chars []int8) string {
+	b := make([]byte, 0, len(chars))
+	for _, c := range chars {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
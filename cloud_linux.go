@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"syscall"
+)
+
+// cloudFUSEDrivers maps the FUSE driver names we've seen in mountinfo
+// (fstype "fuse.<driver>" or the mount source) to the service behind them
+var cloudFUSEDrivers = map[string]string{
+	"rclone":                 "rclone",
+	"onedriver":              "OneDrive",
+	"dropbox":                "Dropbox",
+	"dbxfs":                  "Dropbox",
+	"maestral":               "Dropbox",
+	"google-drive-ocamlfuse": "Google Drive",
+	"gdfs":                   "Google Drive",
+	"gcsfuse":                "Google Cloud Storage",
+	"s3fs":                   "Amazon S3",
+	"goofys":                 "Amazon S3",
+	"blobfuse":               "Azure Blob Storage",
+	"blobfuse2":              "Azure Blob Storage",
+}
+
+// cloudMountType returns the cloud service path is mounted from,
+// or "" if it's on a regular filesystem
+func cloudMountType(path string) string {
+	m, ok := findMount(path)
+	if !ok {
+		return ""
+	}
+
+	fstype := strings.ToLower(m.FSType)
+	if fstype != "fuse" && !strings.HasPrefix(fstype, "fuse.") && fstype != "fuseblk" {
+		return ""
+	}
+
+	driver := strings.TrimPrefix(fstype, "fuse.")
+	if service, ok := cloudFUSEDrivers[driver]; ok {
+		return service
+	}
+
+	// plain "fuse" mounts only tell us the driver through the source
+	source := strings.ToLower(m.Source)
+	for name, service := range cloudFUSEDrivers {
+		if strings.Contains(source, name) {
+			return service
+		}
+	}
+	return ""
+}
+
+// Linux has no dataless flag, unmaterialized files are detected by block count
+func isDataless(st *syscall.Stat_t) bool {
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !linux && !darwin
+
+package main
+
+import "syscall"
+
+// No cloud mount detection on this platform yet
+func cloudMountType(path string) string {
+	return ""
+}
+
+func isDataless(st *syscall.Stat_t) bool {
+	return false
+}
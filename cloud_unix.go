@@ -0,0 +1,50 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// stat(2) always counts blocks in 512-byte units, whatever the filesystem block size
+const statBlockSize = 512
+
+// IsCloudPlaceholder returns true if the file's content is not (fully) stored
+// on the local disk yet:
+//   - macOS File Provider "dataless" files (iCloud Drive, OneDrive, Dropbox)
+//   - files that report a size but have no blocks allocated (sparse/unmaterialized)
+//   - files on a FUSE cloud mount (rclone, onedriver, Dropbox, ...) that are
+//     only partly downloaded
+func IsCloudPlaceholder(path string) bool {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return false
+	}
+
+	if isDataless(&st) {
+		return true
+	}
+
+	size := int64(st.Size)
+	allocated := int64(st.Blocks) * statBlockSize
+
+	// Has a size but nothing on disk -> content lives somewhere else
+	if size > 0 && allocated == 0 {
+		return true
+	}
+
+	// the mount lookup reads mountinfo, so only for files that are short on blocks
+	return allocated < size && cloudMountType(path) != ""
+}
+
+// GetRealFileSize returns the logical file size reported by stat(2).
+// Cloud FUSE mounts report the real remote size here even when the
+// content has not been downloaded, so no extra API is needed like on Windows.
+func GetRealFileSize(path string) (int64, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
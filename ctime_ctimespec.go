@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// GetChangeTime returns the inode change time (ctime) from stat(2).
+// The MCP server doesn't report it, so it's read locally like the real size.
+func GetChangeTime(path string) (time.Time, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(st.Ctimespec.Sec), int64(st.Ctimespec.Nsec)), nil
+}
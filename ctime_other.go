@@ -0,0 +1,12 @@
+//go:build !windows && !linux && !openbsd && !solaris && !illumos && !dragonfly && !darwin && !freebsd && !netbsd
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func GetChangeTime(path string) (time.Time, error) {
+	return time.Time{}, fmt.Errorf("ctime not supported on this platform")
+}
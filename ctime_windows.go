@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// GetChangeTime returns the creation time, which is what "ctime" means on
+// Windows (NTFS's own change time isn't exposed through FindFirstFile/Stat)
+func GetChangeTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no file times for %s", path)
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), nil
+}
@@ -2,13 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
-	"unsafe"
 )
 
 type DeletionRecord struct {
@@ -16,8 +15,32 @@ type DeletionRecord struct {
 	FileName          string    `json:"filename"`
 	FileSize          int64     `json:"filesize"`
 	DeletedAt         time.Time `json:"deleted_at"`
-	FileType          string    `json:"file_type`
-	// RecycleBinFilePath string    `json:"recyclebin_file_path`
+	FileType          string    `json:"file_type"`
+	// TrashPath is where the file ended up inside the recycle bin/trash,
+	// needed by --undo to move it back. Empty for records written before
+	// restore support or when the backend couldn't tell.
+	TrashPath string `json:"trash_path,omitempty"`
+
+	// SessionID groups the files deleted in one --delete run
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// UnmarshalJSON also reads "FileType": the tag of FileType used to be
+// malformed, so older history files stored it under the field name
+func (r *DeletionRecord) UnmarshalJSON(data []byte) error {
+	type record DeletionRecord // without this method
+	var rec struct {
+		record
+		LegacyFileType string `json:"FileType"`
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	*r = DeletionRecord(rec.record)
+	if r.FileType == "" {
+		r.FileType = rec.LegacyFileType
+	}
+	return nil
 }
 
 // deletionHistory :Manages the history of deleted files
@@ -25,83 +48,28 @@ type DeletionHistory struct {
 	Records []DeletionRecord
 }
 
-// Windows API structures for recycle bin
-type _SHFILEOPSTRUCT struct {
-	Hwnd                  uintptr
-	WFunc                 uint32
-	PFrom                 *uint16
-	PTto                  *uint16
-	FFlags                uint16
-	FAnyOperationsAborted bool
-	HNameMappings         uintptr
-	LpszProgressTitle     *uint16
-}
+// TrashBackend moves files somewhere the user can restore them from.
+// DeleteFile never removes a file permanently, it always goes through one of these.
+type TrashBackend interface {
+	// Name is what the user calls it ("Recycle Bin", "Trash")
+	Name() string
 
-// Windows API constants
-const (
-	FO_DELETE          = 0x0003
-	FOF_ALLOWUNDO      = 0x0040
-	FOF_NOCONFIRMATION = 0x0010
-	FOF_SILENT         = 0x0004
-)
-
-// Windows API DLL imports
-var (
-	shell32             = syscall.NewLazyDLL("shell32.dll")
-	procSHFileOperation = shell32.NewProc("SHFileOperationW")
-)
-
-//MovetoRecycleBin moves file to the recycle Bin
-
-// MoveToRecycleBin deletes the given file by delegating the operation to the
-// Windows Shell, causing the file to be moved to the Windows Recycle Bin
-// instead of being permanently removed.
-//
-// This function uses the native Windows API (SHFileOperationW) because Go's
-// standard library does not provide a way to interact with the Recycle Bin.
-// Using the Shell API ensures Explorer-consistent behavior, including:
-//   - Support for undo / restore operations
-//   - Proper handling of OneDrive and cloud-placeholder files
-//   - Correct metadata preservation required by the Recycle Bin
-//
-// The implementation appears complex because it must:
-//   - Convert file paths to UTF-16 (required by Windows APIs)
-//   - Use a Windows-defined struct with an exact memory layout
-//   - Call into a system DLL via syscall and unsafe.Pointer
-//
-// This complexity is inherent to the Windows API boundary and is not business
-// logic. The function should be treated as platform-specific glue code and
-// generally not modified unless the underlying Windows API changes.
-//
-// Note: This function is Windows-only
+	// Trash moves the file (or directory) at path into the trash and
+	// returns where it ended up ("" if the backend can't tell)
+	Trash(path string) (string, error)
 
-func MoveToRecycleBin(filePath string) error {
-	// SHFileOperationW requires double-null terminated string
-	// First, convert to UTF-16
-	pathUTF16, err := syscall.UTF16FromString(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to convert path: %v", err)
-	}
-	
-	// UTF16FromString already adds one null terminator
-	// Append another null for double-null termination
-	pathUTF16 = append(pathUTF16, 0)
-
-	// Set up file operation structure
-	shFileOp := &_SHFILEOPSTRUCT{
-		WFunc:  FO_DELETE,
-		PFrom:  &pathUTF16[0],
-		FFlags: FOF_ALLOWUNDO | FOF_NOCONFIRMATION | FOF_SILENT,
-	}
+	// Restore moves a trashed file back to originalPath and removes any
+	// bookkeeping the backend keeps for it (e.g. .trashinfo, $I file).
+	// The parent directory of originalPath must already exist.
+	Restore(trashPath, originalPath string) error
+}
 
-	// Call Windows API
-	ret, _, _ := procSHFileOperation.Call(uintptr(unsafe.Pointer(shFileOp)))
-	if ret != 0 {
-		return fmt.Errorf("SHFileOperation failed with code: %d", ret)
-	}
+// ErrRestoreConflict is returned when a new file already took the original path
+var ErrRestoreConflict = errors.New("a file already exists at the original location")
 
-	return nil
-}
+// trashBackend is the backend DeleteFile uses, picked per platform
+// by newTrashBackend (trash_windows.go, trash_unix.go, trash_darwin.go)
+var trashBackend TrashBackend = newTrashBackend()
 
 // The following function GetRecycleBinPath() was an early attempt to locate the Windows Recycle Bin
 // by guessing common filesystem paths under the user profile.
@@ -145,34 +113,43 @@ func MoveToRecycleBin(filePath string) error {
 // }
 
 
-// DeleteFile safely moves a file to recycle bin and records it
-func DeleteFile(fileInfo FileInfo, history *DeletionHistory) error {
+// DeleteFile safely moves a file to the recycle bin/trash and records it
+// under the given deletion session
+func DeleteFile(fileInfo FileInfo, history *DeletionHistory, sessionID string) error {
+	// Only ever delete exactly what the server listed
+	if !fileInfo.ListedVerbatim() {
+		return fmt.Errorf("refusing to delete %s: %s", fileInfo.Path, fileInfo.PathCorrection)
+	}
+	if fileInfo.Remote {
+		return fmt.Errorf("refusing to delete %s: it is on the remote server, not here", fileInfo.Path)
+	}
+
+	// The server may report the path in another form (URI, WSL mount)
+	path := localPath(fileInfo.Path)
+
 	// Check if file exists
-	if _, err := os.Stat(fileInfo.Path); os.IsNotExist(err) {
-		return fmt.Errorf("file does not exist: %s", fileInfo.Path)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("file does not exist: %s", path)
 	}
 	
 
-	// Move to recycle bin
-	if err := MoveToRecycleBin(fileInfo.Path); err != nil {
-		return fmt.Errorf("failed to move file to recycle bin: %v", err)
+	// Move to recycle bin / trash
+	trashPath, err := trashBackend.Trash(path)
+	if err != nil {
+		return fmt.Errorf("failed to move file to %s: %v", strings.ToLower(trashBackend.Name()), err)
 	}
 
 	// Create deletion record
 	record := DeletionRecord{
-		OrigionalFilePath: fileInfo.Path,
-		// RecycleBinPath: "", // Will try to find this
-		FileName:  getFileName(fileInfo.Path),
+		OrigionalFilePath: path,
+		TrashPath:         trashPath,
+		SessionID:         sessionID,
+		FileName:          getFileName(fileInfo.Path),
 		FileSize:  fileInfo.SizeBytes,
 		DeletedAt: time.Now(),
-		FileType:  getFileType(fileInfo.Path),
+		FileType:  getFileType(&fileInfo),
 	}
 
-	// // Try to find recycle bin path
-	// if recyclePath, err := GetRecycleBinPath(); err == nil {
-	// 	record.RecycleBinPath = recyclePath
-	// }
-
 	// Add to history
 	history.Records = append(history.Records, record)
 
@@ -238,45 +215,74 @@ func ShowHistory(history *DeletionHistory) {
 		fmt.Printf("   Type: %s\n", record.FileType)
 		fmt.Printf("   Deleted: %s\n", record.DeletedAt.Format("2006-01-02 15:04:05"))
 		fmt.Printf("   Original: %s\n", record.OrigionalFilePath)
-		// if record.RecycleBinPath != "" {
-		// 	fmt.Printf("   Recycle Bin: %s\n", record.RecycleBinPath)
-		// }
+		if record.SessionID != "" {
+			fmt.Printf("   Session: %s\n", record.SessionID)
+		}
+		if record.TrashPath != "" {
+			fmt.Printf("   %s: %s\n", trashBackend.Name(), record.TrashPath)
+		}
 		fmt.Println()
 	}
 }
 
 
-// UndoLastDeletion attempts to restore the last deleted file
+// UndoLastDeletion restores the last deleted file to its original path.
+// The record is only dropped from history once the file is back.
 func UndoLastDeletion(history *DeletionHistory) error {
 	if len(history.Records) == 0 {
 		return fmt.Errorf("no files to undo")
 	}
 
 	lastRecord := history.Records[len(history.Records)-1]
-	
+
 	fmt.Printf(ColorYellow+"Attempting to restore: %s"+ColorReset+"\n", lastRecord.FileName)
-	
-	// Note: Actual restore from recycle bin is complex and requires
-	// Windows shell APIs. For now, we'll show where the file was moved
-	fmt.Printf("File was moved to recycle bin from: %s\n", lastRecord.OrigionalFilePath)
-	fmt.Printf("Check your recycle bin to restore it manually.\n") //for now 
-	
-	// Remove that from history(make sure that file is removed manually)
+
+	if err := RestoreRecord(lastRecord); err != nil {
+		return err
+	}
+	fmt.Printf("Restored to: %s\n", lastRecord.OrigionalFilePath)
+
 	history.Records = history.Records[:len(history.Records)-1]
-	
+
 	return nil
 }
 
+// RestoreRecord moves a deleted file back from the recycle bin/trash,
+// recreating missing parent directories on the way.
+// It refuses to overwrite anything that now lives at the original path.
+func RestoreRecord(record DeletionRecord) error {
+	if record.TrashPath == "" {
+		return fmt.Errorf("no %s location recorded for %s, restore it manually",
+			strings.ToLower(trashBackend.Name()), record.OrigionalFilePath)
+	}
+
+	if _, err := os.Lstat(record.TrashPath); err != nil {
+		return fmt.Errorf("file is no longer in the %s: %v", strings.ToLower(trashBackend.Name()), err)
+	}
+
+	if _, err := os.Lstat(record.OrigionalFilePath); err == nil {
+		return fmt.Errorf("%w: %s", ErrRestoreConflict, record.OrigionalFilePath)
+	}
 
+	if err := os.MkdirAll(filepath.Dir(record.OrigionalFilePath), 0755); err != nil {
+		return fmt.Errorf("failed to recreate parent directory: %v", err)
+	}
+
+	if err := trashBackend.Restore(record.TrashPath, record.OrigionalFilePath); err != nil {
+		return fmt.Errorf("failed to restore file: %v", err)
+	}
+
+	return nil
+}
 
 // ConfirmDeletion asks user for confirmation before deleting
 func ConfirmDeletion(fileInfo FileInfo) bool {
-	fmt.Printf("\n" + ColorRed + "Delete this file?" + ColorReset + "\n")
+	fmt.Print("\n" + ColorRed + "Delete this file?" + ColorReset + "\n")
 	fmt.Printf("Name: %s\n", getFileName(fileInfo.Path))
 	fmt.Printf("Size: %s\n", formatFileSize(fileInfo.SizeBytes))
-	fmt.Printf("Type: %s\n", getFileType(fileInfo.Path))
+	fmt.Printf("Type: %s\n", getFileType(&fileInfo))
 	fmt.Printf("Path: %s\n", fileInfo.Path)
-	fmt.Printf("\n" + ColorYellow + "Are you sure? (y/N): " + ColorReset)
+	fmt.Print("\n" + ColorYellow + "Are you sure? (y/N): " + ColorReset)
 
 	var response string
 	fmt.Scanln(&response)
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadHistoryReadsLegacyFileType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	data := `{"Records": [
+		{"origional_file_path": "/docs/old.pdf", "filename": "old.pdf", "FileType": "PDF"},
+		{"origional_file_path": "/docs/new.pdf", "filename": "new.pdf", "file_type": "Document"}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Records) != 2 {
+		t.Fatalf("got %d records, want 2", len(history.Records))
+	}
+	if got := history.Records[0].FileType; got != "PDF" {
+		t.Errorf("legacy record FileType = %q, want %q", got, "PDF")
+	}
+	if got := history.Records[1].FileType; got != "Document" {
+		t.Errorf("FileType = %q, want %q", got, "Document")
+	}
+	if got := history.Records[0].OrigionalFilePath; got != "/docs/old.pdf" {
+		t.Errorf("OrigionalFilePath = %q", got)
+	}
+
+	// saving writes the fixed key, loading it again keeps the type
+	if err := SaveHistory(history, path); err != nil {
+		t.Fatal(err)
+	}
+	again, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.Records[0].FileType; got != "PDF" {
+		t.Errorf("FileType after save = %q, want %q", got, "PDF")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// partialHashSize is how much of the start of a file the second pass hashes.
+// Most same-size files that differ already differ in their first bytes.
+const partialHashSize = 16 * 1024
+
+// KeepPolicy decides which member of a duplicate set is kept
+type KeepPolicy string
+
+const (
+	KeepNewest KeepPolicy = "newest" // most recently modified copy stays
+	KeepOldest KeepPolicy = "oldest" // original (oldest) copy stays
+)
+
+func parseKeepPolicy(s string) (KeepPolicy, error) {
+	switch p := KeepPolicy(s); p {
+	case KeepNewest, KeepOldest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown keep policy %q (use newest or oldest)", s)
+}
+
+// DuplicateSet is a group of files with identical content
+type DuplicateSet struct {
+	Hash   string // full SHA-256 of the content
+	Size   int64
+	Keep   KeepPolicy
+	Keeper *FileInfo
+	Files  []*FileInfo // all members, keeper included, sorted by path
+}
+
+// Reclaimable is the space freed by deleting every copy but the keeper
+func (d *DuplicateSet) Reclaimable() int64 {
+	return d.Size * int64(len(d.Files)-1)
+}
+
+// DuplicateIndex maps the path of every file in a duplicate set to its set
+type DuplicateIndex map[string]*DuplicateSet
+
+// FindDuplicates groups files with identical content in three passes so
+// only real candidates get fully read:
+//  1. same size
+//  2. same SHA-256 of the first partialHashSize bytes
+//  3. same SHA-256 of the whole file
+//
+// Directories, empty files (the zero-byte rule covers those) and cloud
+// placeholders (reading them would trigger a download) are skipped. So are
+// symlinks and further hard links to a file already counted: they are the
+// same file, not a copy, and deleting them frees nothing.
+// Files that can't be read are left out of the result.
+func FindDuplicates(files []*FileInfo, keep KeepPolicy) []*DuplicateSet {
+	bySize := map[int64][]*FileInfo{}
+	statsBySize := map[int64][]os.FileInfo{}
+	seen := map[string]bool{} // overlapping scan roots list a file twice
+	for _, f := range files {
+		// remote files can't be read from here to compare contents
+		if f.IsDirectory || f.Remote || f.SizeBytes == 0 || seen[f.Path] {
+			continue
+		}
+		seen[f.Path] = true
+
+		local := localPath(f.Path)
+		st, err := os.Lstat(local)
+		if err != nil || st.Mode()&os.ModeSymlink != 0 || isLinkOf(st, statsBySize[f.SizeBytes]) {
+			continue
+		}
+		if IsCloudPlaceholder(local) {
+			continue
+		}
+		statsBySize[f.SizeBytes] = append(statsBySize[f.SizeBytes], st)
+		bySize[f.SizeBytes] = append(bySize[f.SizeBytes], f)
+	}
+
+	var sets []*DuplicateSet
+	for size, sameSize := range bySize {
+		if len(sameSize) < 2 {
+			continue
+		}
+
+		for _, samePartial := range groupByHash(sameSize, partialHashSize) {
+			// files no bigger than the partial hash were already read in full
+			if size <= partialHashSize {
+				sets = append(sets, newDuplicateSet(samePartial, size, keep))
+				continue
+			}
+			for _, sameFull := range groupByHash(samePartial.files, -1) {
+				sets = append(sets, newDuplicateSet(sameFull, size, keep))
+			}
+		}
+	}
+
+	// biggest waste first, path as tie-breaker so output is stable
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].Reclaimable() != sets[j].Reclaimable() {
+			return sets[i].Reclaimable() > sets[j].Reclaimable()
+		}
+		return sets[i].Files[0].Path < sets[j].Files[0].Path
+	})
+	return sets
+}
+
+// isLinkOf reports whether st is a hard link to one of the files in stats
+// (same device and inode)
+func isLinkOf(st os.FileInfo, stats []os.FileInfo) bool {
+	for _, other := range stats {
+		if os.SameFile(st, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashGroup is a set of files sharing one hash
+type hashGroup struct {
+	hash  string
+	files []*FileInfo
+}
+
+// groupByHash hashes the first limit bytes of every file (limit < 0 = whole
+// file) and returns the groups of 2+ files that share a hash
+func groupByHash(files []*FileInfo, limit int64) []hashGroup {
+	byHash := map[string][]*FileInfo{}
+	var order []string
+
+	for _, f := range files {
+		sum, err := hashFile(f.Path, limit)
+		if err != nil {
+			continue
+		}
+		if _, seen := byHash[sum]; !seen {
+			order = append(order, sum)
+		}
+		byHash[sum] = append(byHash[sum], f)
+	}
+
+	var groups []hashGroup
+	for _, sum := range order {
+		if len(byHash[sum]) > 1 {
+			groups = append(groups, hashGroup{hash: sum, files: byHash[sum]})
+		}
+	}
+	return groups
+}
+
+// hashFile returns the hex SHA-256 of the first limit bytes (limit < 0 = all)
+func hashFile(path string, limit int64) (string, error) {
+	f, err := os.Open(localPath(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newDuplicateSet(group hashGroup, size int64, keep KeepPolicy) *DuplicateSet {
+	members := append([]*FileInfo(nil), group.files...)
+	sort.Slice(members, func(i, j int) bool { return members[i].Path < members[j].Path })
+
+	keeper := members[0]
+	for _, f := range members[1:] {
+		switch keep {
+		case KeepOldest:
+			if f.ModifiedAt.Before(keeper.ModifiedAt) {
+				keeper = f
+			}
+		default:
+			if f.ModifiedAt.After(keeper.ModifiedAt) {
+				keeper = f
+			}
+		}
+	}
+
+	return &DuplicateSet{
+		Hash:   group.hash,
+		Size:   size,
+		Keep:   keep,
+		Keeper: keeper,
+		Files:  members,
+	}
+}
+
+// NewDuplicateIndex indexes sets by member path for ExplainDuplicate
+func NewDuplicateIndex(sets []*DuplicateSet) DuplicateIndex {
+	index := DuplicateIndex{}
+	for _, set := range sets {
+		for _, f := range set.Files {
+			index[f.Path] = set
+		}
+	}
+	return index
+}
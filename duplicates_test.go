@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dupFile writes content to dir/name and returns its FileInfo
+func dupFile(t *testing.T, dir, name, content string, mod time.Time) *FileInfo {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &FileInfo{Path: path, SizeBytes: int64(len(content)), ModifiedAt: mod, IsFile: true}
+}
+
+func setPaths(set *DuplicateSet) []string {
+	var names []string
+	for _, f := range set.Files {
+		names = append(names, filepath.Base(f.Path))
+	}
+	return names
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := old.Add(24 * time.Hour)
+
+	big := strings.Repeat("x", partialHashSize+10)
+	files := []*FileInfo{
+		dupFile(t, dir, "a.txt", "same", old),
+		dupFile(t, dir, "b.txt", "same", newer),
+		dupFile(t, dir, "c.txt", "diff", old),     // same size, other content
+		dupFile(t, dir, "big1.bin", big+"1", old), // same first partialHashSize bytes
+		dupFile(t, dir, "big2.bin", big+"2", old), // but a different end
+		dupFile(t, dir, "empty1", "", old),        // zero-byte files are skipped
+		dupFile(t, dir, "empty2", "", old),
+	}
+
+	sets := FindDuplicates(files, KeepNewest)
+	if len(sets) != 1 {
+		t.Fatalf("got %d sets, want 1", len(sets))
+	}
+	if got := strings.Join(setPaths(sets[0]), ","); got != "a.txt,b.txt" {
+		t.Errorf("set = %s, want a.txt,b.txt", got)
+	}
+	if got := filepath.Base(sets[0].Keeper.Path); got != "b.txt" {
+		t.Errorf("newest keeper = %s, want b.txt", got)
+	}
+
+	sets = FindDuplicates(files, KeepOldest)
+	if got := filepath.Base(sets[0].Keeper.Path); got != "a.txt" {
+		t.Errorf("oldest keeper = %s, want a.txt", got)
+	}
+}
+
+func TestFindDuplicatesSkipsLinks(t *testing.T) {
+	dir := t.TempDir()
+	mod := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	target := dupFile(t, dir, "b_real.bin", "content", mod)
+	if err := os.Symlink(target.Path, filepath.Join(dir, "a_link.bin")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	link := &FileInfo{Path: filepath.Join(dir, "a_link.bin"), SizeBytes: target.SizeBytes, ModifiedAt: mod.Add(time.Hour)}
+
+	// a symlink and its target alone are no duplicates
+	if sets := FindDuplicates([]*FileInfo{link, target}, KeepNewest); len(sets) != 0 {
+		t.Fatalf("symlink reported as a copy: %v", setPaths(sets[0]))
+	}
+
+	// neither is a hard link
+	hard := &FileInfo{Path: filepath.Join(dir, "c_hard.bin"), SizeBytes: target.SizeBytes, ModifiedAt: mod.Add(time.Hour)}
+	if err := os.Link(target.Path, hard.Path); err != nil {
+		t.Skip("hard links not supported:", err)
+	}
+	if sets := FindDuplicates([]*FileInfo{target, hard}, KeepNewest); len(sets) != 0 {
+		t.Fatalf("hard link reported as a copy: %v", setPaths(sets[0]))
+	}
+
+	// a real copy still is, and is paired with the real file only
+	copied := dupFile(t, dir, "d_copy.bin", "content", mod.Add(2*time.Hour))
+	sets := FindDuplicates([]*FileInfo{link, target, hard, copied}, KeepOldest)
+	if len(sets) != 1 {
+		t.Fatalf("got %d sets, want 1", len(sets))
+	}
+	if got := strings.Join(setPaths(sets[0]), ","); got != "b_real.bin,d_copy.bin" {
+		t.Errorf("set = %s, want b_real.bin,d_copy.bin", got)
+	}
+	if sets[0].Keeper != target {
+		t.Errorf("keeper = %s, want the real file", sets[0].Keeper.Path)
+	}
+}
+
+func TestFindDuplicatesURIPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file:// URI of a Unix temp dir")
+	}
+	dir := t.TempDir()
+	a := dupFile(t, dir, "a b.txt", "same", time.Time{})
+	b := dupFile(t, dir, "c.txt", "same", time.Time{})
+	// some servers hand back file:// URIs instead of paths
+	a.Path = "file://" + strings.ReplaceAll(a.Path, " ", "%20")
+	b.Path = "file://" + b.Path
+
+	sets := FindDuplicates([]*FileInfo{a, b}, KeepNewest)
+	if len(sets) != 1 || len(sets[0].Files) != 2 {
+		t.Fatalf("got %d sets, want one set of both files", len(sets))
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileCategory is one entry of the type catalog: the extensions and MIME
+// types that make a file a "pdf", an "image", ...
+type FileCategory struct {
+	Name       string   `json:"name"`              // what --filter takes
+	Label      string   `json:"label,omitempty"`   // how output names it, Name if empty
+	Aliases    []string `json:"aliases,omitempty"` // other names --filter accepts
+	Extensions []string `json:"extensions,omitempty"`
+	MimeTypes  []string `json:"mime_types,omitempty"` // "image/*" matches a whole family
+}
+
+// TypeCatalog holds the categories in the order files are named by:
+// a file belongs to every category listing it (so --filter doc includes
+// PDFs) but is labeled by the first one.
+type TypeCatalog struct {
+	Categories []FileCategory `json:"categories"`
+}
+
+// defaultTypeCatalog is used as it is unless a catalog file overrides it
+var defaultTypeCatalog = TypeCatalog{Categories: []FileCategory{
+	{
+		Name: "pdf", Label: "PDF", Aliases: []string{"pdfs"},
+		Extensions: []string{".pdf"},
+		MimeTypes:  []string{"application/pdf"},
+	},
+	{
+		Name: "image", Label: "Image", Aliases: []string{"images", "img", "jpg", "jpeg", "png", "gif"},
+		Extensions: []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tif", ".tiff", ".heic", ".svg"},
+		MimeTypes:  []string{"image/*"},
+	},
+	{
+		Name: "video", Label: "Video", Aliases: []string{"videos", "mp4", "avi"},
+		Extensions: []string{".mp4", ".avi", ".mkv", ".mov", ".wmv", ".webm"},
+		MimeTypes:  []string{"video/*"},
+	},
+	{
+		Name: "audio", Label: "Audio", Aliases: []string{"music", "mp3"},
+		Extensions: []string{".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a"},
+		MimeTypes:  []string{"audio/*"},
+	},
+	{
+		Name: "archive", Label: "Archive", Aliases: []string{"archives", "zip", "tar", "rar", "7z"},
+		Extensions: []string{".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".rar", ".7z"},
+		MimeTypes: []string{"application/zip", "application/x-tar", "application/gzip", "application/x-bzip2",
+			"application/x-xz", "application/vnd.rar", "application/x-rar-compressed", "application/x-7z-compressed"},
+	},
+	{
+		Name: "code", Label: "Code", Aliases: []string{"source", "src"},
+		Extensions: []string{".go", ".py", ".js", ".ts", ".java", ".c", ".h", ".cpp", ".cs", ".rb", ".rs",
+			".php", ".sh", ".ps1", ".html", ".css", ".json", ".yaml", ".yml", ".xml", ".sql"},
+		MimeTypes: []string{"text/x-*", "application/javascript", "application/json", "application/xml", "text/html", "text/css"},
+	},
+	{
+		Name: "text", Label: "Text", Aliases: []string{"txt"},
+		Extensions: []string{".txt", ".md", ".log", ".csv"},
+		MimeTypes:  []string{"text/plain", "text/markdown", "text/csv"},
+	},
+	{
+		Name: "document", Label: "Document", Aliases: []string{"documents", "docs", "doc", "docx"},
+		Extensions: []string{".doc", ".docx", ".odt", ".rtf", ".xls", ".xlsx", ".ods", ".ppt", ".pptx", ".odp", ".pdf", ".txt"},
+		MimeTypes: []string{"application/msword", "application/rtf", "application/vnd.ms-*", "application/pdf",
+			"application/vnd.openxmlformats-officedocument.*", "application/vnd.oasis.opendocument.*"},
+	},
+}}
+
+// typeCatalog is what filtering and output use, the defaults until run()
+// loads a catalog file
+var typeCatalog = &defaultTypeCatalog
+
+// LoadTypeCatalog reads a catalog file over the defaults, YAML if it ends
+// in .yaml or .yml and JSON otherwise. A category with a built-in name
+// replaces it, a new name is added at the end:
+//
+//	{"categories": [
+//	  {"name": "image", "extensions": [".jpg", ".png", ".raw"], "mime_types": ["image/*"]},
+//	  {"name": "cad", "label": "CAD", "extensions": [".dwg", ".dxf"]}
+//	]}
+func LoadTypeCatalog(path string) (*TypeCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file TypeCatalog
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoded, err := decodeCatalogYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type catalog %s: %v", path, err)
+		}
+		file = *decoded
+	default:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid type catalog %s: %v", path, err)
+		}
+	}
+
+	catalog := &TypeCatalog{Categories: append([]FileCategory(nil), defaultTypeCatalog.Categories...)}
+	for _, cat := range file.Categories {
+		cat.Name = strings.ToLower(strings.TrimSpace(cat.Name))
+		if cat.Name == "" || cat.Name == "all" {
+			return nil, fmt.Errorf("invalid type catalog %s: category without a usable name", path)
+		}
+		for i, ext := range cat.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			cat.Extensions[i] = strings.ToLower(ext)
+		}
+
+		replaced := false
+		for i := range catalog.Categories {
+			if catalog.Categories[i].Name == cat.Name {
+				catalog.Categories[i] = cat
+				replaced = true
+			}
+		}
+		if !replaced {
+			catalog.Categories = append(catalog.Categories, cat)
+		}
+	}
+	return catalog, nil
+}
+
+// DefaultTypeCatalogPath is where the catalog is looked for when
+// --types isn't given; types.yaml next to it is tried too
+func DefaultTypeCatalogPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "filesystem-analyzer", "types.json")
+}
+
+// resolveTypeCatalog loads --types, or the default path if it exists
+func resolveTypeCatalog() (*TypeCatalog, error) {
+	path := typesPath
+	if path == "" {
+		if def := DefaultTypeCatalogPath(); def != "" {
+			base := strings.TrimSuffix(def, ".json")
+			for _, candidate := range []string{def, base + ".yaml", base + ".yml"} {
+				if _, err := os.Stat(candidate); err == nil {
+					path = candidate
+					break
+				}
+			}
+		}
+	}
+	if path == "" {
+		return &defaultTypeCatalog, nil
+	}
+	return LoadTypeCatalog(path)
+}
+
+// Lookup finds a category by name or alias
+func (c *TypeCatalog) Lookup(name string) *FileCategory {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i := range c.Categories {
+		cat := &c.Categories[i]
+		if cat.Name == name {
+			return cat
+		}
+		for _, alias := range cat.Aliases {
+			if strings.ToLower(alias) == name {
+				return cat
+			}
+		}
+	}
+	return nil
+}
+
+// ParseFilter turns a --filter value like "pdf,image" into categories;
+// nil means every file ("all" or an empty spec). Empty elements from a
+// stray comma are skipped, they don't widen the filter.
+func (c *TypeCatalog) ParseFilter(spec string) ([]*FileCategory, error) {
+	var cats []*FileCategory
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.EqualFold(name, "all") {
+			return nil, nil
+		}
+		cat := c.Lookup(name)
+		if cat == nil {
+			return nil, fmt.Errorf("unknown --filter category %q (available: all, %s)", name, strings.Join(c.Names(), ", "))
+		}
+		cats = append(cats, cat)
+	}
+	return cats, nil
+}
+
+// Names lists the category names, sorted
+func (c *TypeCatalog) Names() []string {
+	names := make([]string, len(c.Categories))
+	for i, cat := range c.Categories {
+		names[i] = cat.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Classify returns the category that names a file, nil if none does.
+// The extension decides; the MIME type only when there is no extension
+// or the catalog doesn't know it.
+func (c *TypeCatalog) Classify(path, mimeType string) *FileCategory {
+	ext := strings.ToLower(getExtension(getFileName(path)))
+	for i := range c.Categories {
+		if c.Categories[i].hasExtension(ext) {
+			return &c.Categories[i]
+		}
+	}
+	return c.byMimeType(mimeType)
+}
+
+// ClassifyFile is Classify for a scanned file. A sniffed content type that
+// a category knows comes first, so a PNG renamed to .pdf is an image.
+func (c *TypeCatalog) ClassifyFile(info *FileInfo) *FileCategory {
+	if cat := c.byMimeType(info.DetectedMimeType); cat != nil {
+		return cat
+	}
+	return c.Classify(info.Path, info.MimeType)
+}
+
+func (c *TypeCatalog) byMimeType(mimeType string) *FileCategory {
+	for i := range c.Categories {
+		if c.Categories[i].hasMimeType(mimeType) {
+			return &c.Categories[i]
+		}
+	}
+	return nil
+}
+
+// HasExtension reports whether any category lists ext
+func (c *TypeCatalog) HasExtension(ext string) bool {
+	for i := range c.Categories {
+		if c.Categories[i].hasExtension(ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsFile is Contains for a scanned file, by the same rule as
+// ClassifyFile
+func (cat *FileCategory) ContainsFile(catalog *TypeCatalog, info *FileInfo) bool {
+	if catalog.byMimeType(info.DetectedMimeType) != nil {
+		return cat.hasMimeType(info.DetectedMimeType)
+	}
+	return cat.Contains(catalog, info.Path, info.MimeType)
+}
+
+// Contains reports whether the file belongs to the category, by the same
+// rule as Classify
+func (cat *FileCategory) Contains(catalog *TypeCatalog, path, mimeType string) bool {
+	ext := strings.ToLower(getExtension(getFileName(path)))
+	for i := range catalog.Categories {
+		if catalog.Categories[i].hasExtension(ext) {
+			return cat.hasExtension(ext)
+		}
+	}
+	return cat.hasMimeType(mimeType)
+}
+
+// DisplayName is the label, or the name without one
+func (cat *FileCategory) DisplayName() string {
+	if cat.Label != "" {
+		return cat.Label
+	}
+	return cat.Name
+}
+
+func (cat *FileCategory) hasExtension(ext string) bool {
+	if ext == "" {
+		return false
+	}
+	for _, e := range cat.Extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cat *FileCategory) hasMimeType(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i]) // "text/plain; charset=utf-8"
+	}
+	if mimeType == "" {
+		return false
+	}
+	for _, m := range cat.MimeTypes {
+		m = strings.ToLower(m)
+		if prefix, ok := strings.CutSuffix(m, "*"); ok {
+			if strings.HasPrefix(mimeType, prefix) {
+				return true
+			}
+		} else if m == mimeType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string // category names, "<all>" for nil
+	}{
+		{"", "<all>"},
+		{"  ", "<all>"},
+		{",", "<all>"},
+		{"all", "<all>"},
+		{"ALL", "<all>"},
+		{"pdf,all", "<all>"},
+		{"pdf", "pdf"},
+		{"pdf,", "pdf"},
+		{",pdf", "pdf"},
+		{"pdf,,image", "pdf,image"},
+		{" pdf , images ", "pdf,image"},
+	}
+	for _, tt := range tests {
+		cats, err := defaultTypeCatalog.ParseFilter(tt.spec)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.spec, err)
+			continue
+		}
+		got := "<all>"
+		if cats != nil {
+			var names []string
+			for _, cat := range cats {
+				names = append(names, cat.Name)
+			}
+			got = strings.Join(names, ",")
+		}
+		if got != tt.want {
+			t.Errorf("ParseFilter(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+
+	if _, err := defaultTypeCatalog.ParseFilter("pdf,nope"); err == nil {
+		t.Error("ParseFilter accepted an unknown category")
+	}
+}
+
+// writeCatalog puts a catalog file named name in a temp dir
+func writeCatalog(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTypeCatalog(t *testing.T) {
+	files := map[string]string{
+		"types.json": `{"categories": [
+			{"name": " Image ", "extensions": ["JPG", ".raw"], "mime_types": ["image/*"]},
+			{"name": "cad", "label": "CAD", "aliases": ["drawings"], "extensions": ["dwg", ".DXF"], "mime_types": ["application/acad"]}
+		]}`,
+		"types.yaml": `# image loses png and friends
+categories:
+  - name: " Image "
+    extensions: [JPG, .raw]
+    mime_types:
+      - image/*
+  - name: cad   # new
+    label: 'CAD'
+    aliases: [drawings]
+    extensions:
+    - dwg
+    - ".DXF"
+    mime_types: [application/acad]
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			catalog, err := LoadTypeCatalog(writeCatalog(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// image is replaced in place, cad comes after the defaults
+			names := make([]string, len(catalog.Categories))
+			for i, cat := range catalog.Categories {
+				names[i] = cat.Name
+			}
+			want := "pdf,image,video,audio,archive,code,text,document,cad"
+			if got := strings.Join(names, ","); got != want {
+				t.Fatalf("categories %s, want %s", got, want)
+			}
+			image := catalog.Lookup("image")
+			if got := strings.Join(image.Extensions, ","); got != ".jpg,.raw" {
+				t.Errorf("image extensions %s, want .jpg,.raw", got)
+			}
+			if image.Label != "" || len(image.Aliases) != 0 {
+				t.Errorf("image kept default fields: %+v", image)
+			}
+			cad := catalog.Lookup("drawings")
+			if cad == nil || cad.Label != "CAD" || strings.Join(cad.Extensions, ",") != ".dwg,.dxf" {
+				t.Fatalf("cad = %+v", cad)
+			}
+
+			// the defaults themselves are untouched
+			if got := len(defaultTypeCatalog.Lookup("image").Extensions); got < 3 {
+				t.Errorf("default image category changed, %d extensions", got)
+			}
+
+			tests := []struct {
+				path, mime, detected string
+				want                 string // "" for none
+			}{
+				{"a/plan.DWG", "", "", "cad"},
+				{"a/photo.raw", "", "", "image"},
+				{"a/photo.png", "", "", ""}, // png went with the override
+				{"a/photo.png", "image/png", "", "image"},
+				{"a/noext", "application/acad", "", "cad"},
+				{"a/notes.txt", "", "", "text"},
+				{"a/report.pdf", "", "", "pdf"},
+				// a sniffed type beats the extension, the reported one doesn't
+				{"a/report.pdf", "", "image/png", "image"},
+				{"a/plan.dwg", "image/png", "", "cad"},
+				{"a/plan.dwg", "", "application/acad", "cad"},
+				{"a/photo.raw", "", "application/acad", "cad"},
+				{"a/photo.raw", "", "application/x-unknown", "image"},
+			}
+			for _, tt := range tests {
+				got := catalog.ClassifyFile(&FileInfo{Path: tt.path, MimeType: tt.mime, DetectedMimeType: tt.detected})
+				name := ""
+				if got != nil {
+					name = got.Name
+				}
+				if name != tt.want {
+					t.Errorf("ClassifyFile(%s, %q, sniffed %q) = %q, want %q", tt.path, tt.mime, tt.detected, name, tt.want)
+				}
+				if tt.detected == "" {
+					if direct := catalog.Classify(tt.path, tt.mime); direct != got {
+						t.Errorf("Classify(%s, %q) disagrees with ClassifyFile", tt.path, tt.mime)
+					}
+				}
+			}
+
+			cats, err := catalog.ParseFilter("cad,image")
+			if err != nil || len(cats) != 2 || cats[0] != cad || cats[1] != image {
+				t.Errorf("ParseFilter(cad,image) = %v, %v", cats, err)
+			}
+		})
+	}
+}
+
+func TestLoadTypeCatalogErrors(t *testing.T) {
+	tests := []struct{ name, content string }{
+		{"all.json", `{"categories": [{"name": "all", "extensions": [".x"]}]}`},
+		{"all.yaml", "categories:\n  - name: ALL\n"},
+		{"unnamed.json", `{"categories": [{"extensions": [".x"]}]}`},
+		{"blank.yml", "categories:\n  - name: '  '\n"},
+		{"broken.json", `{"categories": [`},
+		{"types.txt", "categories:\n  - name: cad\n"}, // only .yaml/.yml read as YAML
+		{"top.yaml", "types:\n  - name: cad\n"},
+		{"list-name.yaml", "categories:\n  - name: [a, b]\n"},
+		{"open-list.yaml", "categories:\n  - name: cad\n    extensions: [.dwg\n"},
+		{"quote.yaml", "categories:\n  - name: \"cad\n"},
+		{"stray.yaml", "categories:\n  name: cad\n"},
+		{"tabs.yaml", "categories:\n\t- name: cad\n"},
+	}
+	for _, tt := range tests {
+		if _, err := LoadTypeCatalog(writeCatalog(t, tt.name, tt.content)); err == nil {
+			t.Errorf("%s loaded: %q", tt.name, tt.content)
+		}
+	}
+
+	if _, err := LoadTypeCatalog(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing catalog file loaded")
+	}
+}
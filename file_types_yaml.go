@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeCatalogYAML reads the YAML form of a catalog file. Only the shape
+// a catalog has is understood, a "categories" list of mappings whose
+// values are scalars, [flow, lists] or "- item" lists:
+//
+//	categories:
+//	  - name: cad
+//	    label: CAD
+//	    extensions: [.dwg, .dxf]
+//	    mime_types:
+//	      - image/vnd.dwg
+//
+// The result goes through the JSON tags, so both forms check the same way.
+func decodeCatalogYAML(data []byte) (*TypeCatalog, error) {
+	var cats []map[string]any
+	var cur map[string]any
+	itemIndent := -1 // where the "- " of a category sits
+	listKey := ""    // key whose "- item" lines are being read
+	seenTop := false
+
+	for n, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs can't indent YAML", n+1)
+		}
+		indent := len(text) - len(trimmed)
+
+		if !seenTop {
+			key, value, ok := strings.Cut(trimmed, ":")
+			value = strings.TrimSpace(value)
+			if indent != 0 || !ok || strings.TrimSpace(key) != "categories" || (value != "" && value != "[]") {
+				return nil, fmt.Errorf("line %d: expected \"categories:\"", n+1)
+			}
+			seenTop = true
+			continue
+		}
+		if indent == 0 {
+			return nil, fmt.Errorf("line %d: only \"categories\" is understood at the top", n+1)
+		}
+
+		item, isItem := strings.CutPrefix(trimmed, "-")
+		isItem = isItem && (item == "" || item[0] == ' ')
+		switch {
+		case isItem && (itemIndent < 0 || indent == itemIndent):
+			itemIndent = indent
+			cur = map[string]any{}
+			cats = append(cats, cur)
+			listKey = ""
+			if item = strings.TrimSpace(item); item != "" {
+				if err := setYAMLPair(cur, item, &listKey); err != nil {
+					return nil, fmt.Errorf("line %d: %v", n+1, err)
+				}
+			}
+		case isItem && listKey != "" && indent > itemIndent:
+			value, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+			list, _ := cur[listKey].([]string)
+			cur[listKey] = append(list, value)
+		case !isItem && cur != nil && indent > itemIndent:
+			if err := setYAMLPair(cur, trimmed, &listKey); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", n+1, trimmed)
+		}
+	}
+
+	// same checks as a JSON file: a list where a name goes is an error
+	encoded, err := json.Marshal(map[string]any{"categories": cats})
+	if err != nil {
+		return nil, err
+	}
+	var catalog TypeCatalog
+	if err := json.Unmarshal(encoded, &catalog); err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// setYAMLPair stores "key: value" in m. An empty value starts a block
+// list, so listKey is set for the "- item" lines that follow.
+func setYAMLPair(m map[string]any, text string, listKey *string) error {
+	key, value, ok := strings.Cut(text, ":")
+	if !ok {
+		return fmt.Errorf("expected \"key: value\", got %q", text)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	*listKey = ""
+
+	switch {
+	case value == "":
+		m[key] = []string(nil)
+		*listKey = key
+	case strings.HasPrefix(value, "["):
+		inner, ok := strings.CutSuffix(value, "]")
+		if !ok {
+			return fmt.Errorf("unterminated list for %s", key)
+		}
+		list := []string{}
+		if inner = strings.TrimSpace(inner[1:]); inner != "" {
+			for _, part := range strings.Split(inner, ",") {
+				s, err := yamlScalar(strings.TrimSpace(part))
+				if err != nil {
+					return err
+				}
+				list = append(list, s)
+			}
+		}
+		m[key] = list
+	default:
+		s, err := yamlScalar(value)
+		if err != nil {
+			return err
+		}
+		m[key] = s
+	}
+	return nil
+}
+
+// yamlScalar unquotes 'single' and "double" quoted values, plain ones are
+// taken as they are
+func yamlScalar(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'"):
+		return "", fmt.Errorf("unterminated quote in %s", s)
+	}
+	return s, nil
+}
+
+// stripYAMLComment cuts a "# comment" off a line, leaving a # inside
+// quotes or in the middle of a word alone
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -2,9 +2,9 @@ package main
 
 import (
 	"encoding/json"
-	"strconv"
+	"path/filepath"
+	"runtime"
 	"strings"
-	"time"
 )
 
 func GetFileInfo(client *MCPClient, path string) (*FileInfo, error) {
@@ -19,77 +19,59 @@ func GetFileInfo(client *MCPClient, path string) (*FileInfo, error) {
 	if err := json.Unmarshal(raw, &resp); err != nil {
 		return nil, err
 	}
-
-	info := &FileInfo{
-		Path: path,
+	if err := resp.Result.Err(); err != nil {
+		return nil, err
 	}
 
-	for _, item := range resp.Result.Content {
-		if item.Type != "text" {
-			continue
-		}
-
-		lines := strings.Split(item.Text, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
+	info, reported, warnings := ParseFileInfoResult(path, resp.Result)
+	info.ParseWarnings = warnings
+	info.ListedPath = path
+	info.Remote = client.Remote
 
-			switch {
-			case strings.HasPrefix(line, "Size:"):
-				// "Size: 68 bytes"
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					size, err := strconv.ParseInt(parts[1], 10, 64)
-					if err == nil {
-						info.SizeBytes = size
-					}
-				}
-
-			case strings.HasPrefix(line, "Created:"):
-				t := strings.TrimPrefix(line, "Created:")
-				if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(t)); err == nil {
-					info.CreatedAt = parsed
-				}
-
-			case strings.HasPrefix(line, "Modified:"):
-				t := strings.TrimPrefix(line, "Modified:")
-				if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(t)); err == nil {
-					info.ModifiedAt = parsed
-				}
+	// The server may name the entry differently than the listing did
+	// (e.g. resolved case or short names). Use its path, but say so.
+	if reported != "" && !samePath(reported, path) {
+		info.Path = reported
+		info.PathCorrection = "listed as " + path + ", server reported " + reported
+	}
 
-			case strings.HasPrefix(line, "Accessed:"):
-				t := strings.TrimPrefix(line, "Accessed:")
-				if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(t)); err == nil {
-					info.AccessedAt = parsed
-				}
+	// everything below reads the file here, a remote server's path may
+	// name some unrelated local file
+	if info.Remote {
+		return info, nil
+	}
 
-			case strings.HasPrefix(line, "IsFile:"):
-				info.IsFile = strings.Contains(line, "true")
+	// OneDrive placeholders report a size of 0 through the server, the
+	// local API knows the real one. If it can't be read (file gone) the
+	// server's size stays.
+	local := localPath(info.Path)
+	if !info.IsDirectory {
+		if realSize, err := GetRealFileSize(local); err == nil {
+			info.SizeBytes = realSize
+		}
+	}
 
-			case strings.HasPrefix(line, "IsDirectory:"):
-				info.IsDirectory = strings.Contains(line, "true")
+	if changed, err := GetChangeTime(local); err == nil {
+		info.ChangedAt = changed
+	}
 
-			case strings.HasPrefix(line, "MIME Type:"):
-				info.MimeType = strings.TrimSpace(strings.TrimPrefix(line, "MIME Type:"))
-			}
-		}
-		if !info.IsDirectory {
-			realSize, err := GetRealFileSize(path)
-			if err != nil {
-				extensions := []string{".pdf", ".doc", ".docx", ".txt"}
-				for _, ext := range extensions {
-					if size, err2 := GetRealFileSize(path + ext); err2 == nil {
-						realSize = size
-						err = nil
-						info.Path = path + ext
-						break
-					}
-				}
-			}
-			if err == nil {
-				info.SizeBytes = realSize
-			}
+	// the server's MIME type usually comes from the extension, the content
+	// says what the file really is
+	if sniffContent && !info.IsDirectory {
+		if detected, err := SniffMimeType(info.Path); err == nil {
+			info.DetectedMimeType = detected
 		}
 	}
 
 	return info, nil
 }
+
+// samePath compares two paths the way the local filesystem would:
+// cleaned, and case-insensitively on Windows
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
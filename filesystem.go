@@ -5,14 +5,38 @@ import (
 )
 
 type FileInfo struct {
-	Path        string
+	// Path is the file the analyzer works on. It is ListedPath unless the
+	// server reported a different path for the entry, then PathCorrection
+	// says so.
+	Path           string
+	ListedPath     string // exactly as list_directory returned it
+	PathCorrection string // "" when Path == ListedPath
+	RelPath        string // relative to the scan root, "/" separated
+
 	SizeBytes   int64
 	CreatedAt   time.Time
 	ModifiedAt  time.Time
 	AccessedAt  time.Time
+	ChangedAt   time.Time // ctime, read locally (the server doesn't report it)
 	IsFile      bool
 	IsDirectory bool
 	MimeType    string
+	// DetectedMimeType is the type read from the file's first bytes with
+	// --sniff, "" when it wasn't sniffed or isn't recognized
+	DetectedMimeType string
+
+	// ParseWarnings lists metadata the server sent that couldn't be read
+	ParseWarnings []ParseWarning
+
+	// Remote entries came from a --server-url server: only the server's
+	// metadata counts, the local file with that path is a different one
+	Remote bool
+}
+
+// ListedVerbatim reports whether Path is exactly what the listing returned,
+// which --delete insists on
+func (f *FileInfo) ListedVerbatim() bool {
+	return f.ListedPath == "" || f.Path == f.ListedPath
 }
 
 //btw MimeType tells what's the extension of a file ,whether it's .pdf,.txt etc
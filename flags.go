@@ -1,99 +1,69 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 )
 
-type FILTERTYPE int
-
-const (
-	FILTERALL FILTERTYPE = iota
-	FILEPDFS
-	FILTERImages
-	FILTERDocuments
-	FilterArchive
-)
-
 // struct ,a file configuration holds filter settings
 type FilterConfig struct {
-	FileType       FILTERTYPE
-	IncludePattern string
-	ExcludePattern string
-	MinSizeMB      int64
-	MaxSizeMB      int64
+	Categories      []*FileCategory // --filter, nil = all files
+	IncludePatterns stringList // --include, see PatternList
+	ExcludePatterns stringList // --exclude
+	MinSizeMB       int64
+	MaxSizeMB       int64
+
+	// compiled by CompilePatterns
+	include *PatternList
+	exclude *PatternList
 }
 
-func getFileName(path string) string {
-	str := strings.Split(path, "\\")
-	return str[len(str)-1] //returns the filename with extension
+// CompilePatterns parses the --include and --exclude patterns
+func (c *FilterConfig) CompilePatterns() error {
+	include, err := ParsePatterns(c.IncludePatterns)
+	if err != nil {
+		return fmt.Errorf("--include: %v", err)
+	}
+	exclude, err := ParsePatterns(c.ExcludePatterns)
+	if err != nil {
+		return fmt.Errorf("--exclude: %v", err)
+	}
+	c.include, c.exclude = include, exclude
+	return nil
 }
 
-func getExtension(filename string) string {
-	dotIndex := strings.LastIndex(filename, ".")
-	if dotIndex == -1 {
-		return ""
+//what files to include during the scan, by type
+func ShouldInclude(info *FileInfo, config FilterConfig) bool {
+	if len(config.Categories) == 0 {
+		return true
+	}
+	for _, cat := range config.Categories {
+		if cat.ContainsFile(typeCatalog, info) {
+			return true
+		}
 	}
-	return filename[dotIndex:]
+	return false
 }
 
-//parse the extension to the FilterType
-func parserFilterType(a string) FILTERTYPE {
-	str := strings.ToLower(a)
-	switch str {
-	case "pdf":
-		return FILEPDFS
-	case "all":
-		return FILTERALL
-	case "image", "img", "jpg", "png", "gif", "jpeg":
-		return FILTERImages
-	case "docs", "doc", "docx", "txt":
-		return FILTERDocuments
-	case "archive", "zip", "tar", "rar", "7z":
-		return FilterArchive
-	default:
-		return FILTERALL
+// FilterLabel describes the --filter categories for the settings display
+func (c FilterConfig) FilterLabel() string {
+	if len(c.Categories) == 0 {
+		return "All Files"
 	}
-
+	labels := make([]string, len(c.Categories))
+	for i, cat := range c.Categories {
+		labels[i] = cat.DisplayName()
+	}
+	return strings.Join(labels, ", ")
 }
 
-//what files to include during the scan 
-func ShouldInclude(path string, config FilterConfig) bool {
-	filename := getFileName(path)
-	ext := strings.ToLower(getExtension(filename))
-
-	switch config.FileType {
-
-	case FILEPDFS:
-		return ext == ".pdf"
-
-	case FILTERImages:
-		return ext == ".jpg" || ext == ".jpeg" ||
-			ext == ".png" || ext == ".gif"
-
-	case FILTERDocuments:
-		return ext == ".pdf" || ext == ".doc" ||
-			ext == ".docx" || ext == ".txt"
-
-	case FilterArchive:
-		return ext == ".zip" || ext == ".tar" ||
-			ext == ".rar" || ext == ".7z"
-
-	case FILTERALL:
-		return true
-
-	default:
+// ShouldIncludePath applies --include to a path relative to its scan root;
+// without include patterns every path is included
+func ShouldIncludePath(relPath string, config FilterConfig) bool {
+	if config.include.Len() == 0 {
 		return true
 	}
-}
-func ShouldExclude(path string, config FilterConfig) bool {
-	if config.ExcludePattern == "" {
-		return false
-	}
-
-	filename := strings.ToLower(getFileName(path))
-	pattern := strings.ToLower(config.ExcludePattern)
-
-	return strings.Contains(filename, pattern)
+	return config.include.Match(relPath, false)
 }
 
 //optional thp ,allow files which is in the defined limit
@@ -114,20 +84,3 @@ func ShouldIncludeSize(path string, config FilterConfig, size int64) bool {
 
 	return true
 }
-
-func (f FILTERTYPE) String() string {
-	switch f {
-	case FILTERALL:
-		return "All Files"
-	case FILEPDFS:
-		return "PDFs"
-	case FILTERImages:
-		return "Images"
-	case FILTERDocuments:
-		return "Documents"
-	case FilterArchive:
-		return "Archives"
-	default:
-		return "Unknown"
-	}
-}
@@ -2,10 +2,46 @@ package main
 
 import (
 	"encoding/json"
+	"net/url"
 	"regexp"
+	"strings"
 )
 
-func list_directory(client *MCPClient, path string) ([]string, error) {
+// EntryKind is what a listed entry is
+type EntryKind string
+
+const (
+	EntryFile    EntryKind = "file"
+	EntryDir     EntryKind = "dir"
+	EntrySymlink EntryKind = "symlink"
+)
+
+// DirEntry is one entry of a list_directory result
+type DirEntry struct {
+	Path     string
+	Name     string
+	Kind     EntryKind
+	MimeType string // only when the server sent a resource block for it
+}
+
+// listingLineRe matches one entry of the list_directory text:
+//
+//	[FILE] Report (final).pdf (file:///docs/Report (final).pdf) - 68 bytes
+//	[DIR]  Photos (file:///docs/Photos)
+//	[DIR] Photos                     (Node server, no URI)
+//
+// The URI runs to the LAST ")" before the optional size, so names with
+// parentheses keep their full name and extension.
+var listingLineRe = regexp.MustCompile(`^\[(FILE|DIR|LINK|SYMLINK)\]\s+(.*?)(?: \((file://.+)\))?(?: - \d+ bytes)?\s*$`)
+
+var listingKinds = map[string]EntryKind{
+	"FILE":    EntryFile,
+	"DIR":     EntryDir,
+	"LINK":    EntrySymlink,
+	"SYMLINK": EntrySymlink,
+}
+
+func list_directory(client *MCPClient, path string) ([]DirEntry, error) {
 	data, err := client.ToolCall("list_directory", map[string]any{
 		"path": path,
 	})
@@ -18,21 +54,123 @@ func list_directory(client *MCPClient, path string) ([]string, error) {
 	if err := json.Unmarshal(data, &resp); err != nil {
 		return nil, err
 	}
+	if err := resp.Result.Err(); err != nil {
+		return nil, err
+	}
 
-	var files []string
+	return parseListing(path, resp.Result), nil
+}
 
-	// URLs - captures everything until closing parenthesis
-	re := regexp.MustCompile(`file://([^\)]+)`)
+// parseListing reads the entries of dir from a list_directory result.
+// Resource blocks, one per entry, are used when the server sends them;
+// otherwise the "[FILE] name (file://path)" lines of the text blocks.
+// A resource for dir itself (mark3labs sends one) is not an entry.
+func parseListing(dir string, result MCPResult) []DirEntry {
+	var entries []DirEntry
 
-	for _, item := range resp.Result.Content {
-		if item.Type == "text" {
-			matches := re.FindAllStringSubmatch(item.Text, -1)
-			for _, m := range matches {
-				files = append(files, m[1])
+	for _, item := range result.Content {
+		if item.Type != "resource" || item.Resource == nil {
+			continue
+		}
+		path, ok := fileURIPath(item.Resource.URI)
+		if !ok || samePath(path, dir) {
+			continue
+		}
+		entries = append(entries, DirEntry{
+			Path:     path,
+			Name:     getFileName(path),
+			Kind:     resourceKind(item.Resource),
+			MimeType: item.Resource.MimeType,
+		})
+	}
+	if len(entries) > 0 {
+		return entries
+	}
+
+	for _, item := range result.Content {
+		if item.Type != "text" {
+			continue
+		}
+		for _, line := range strings.Split(item.Text, "\n") {
+			m := listingLineRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+			if m == nil {
+				continue
+			}
+			entry := DirEntry{Name: m[2], Kind: listingKinds[m[1]]}
+			if path, ok := fileURIPath(m[3]); ok {
+				entry.Path = path
+			} else {
+				entry.Path = joinPath(dir, m[2])
 			}
+			entries = append(entries, entry)
 		}
 	}
 
-	return files, nil
+	return entries
+}
+
+// resourceKind tells directories and symlinks from files by the MIME type,
+// then the resource text ("Directory: ...", "[DIR] ..."), then a trailing
+// slash on the URI
+func resourceKind(r *MCPResource) EntryKind {
+	switch r.MimeType {
+	case "inode/directory", "text/directory", "application/x-directory":
+		return EntryDir
+	case "inode/symlink":
+		return EntrySymlink
+	}
+
+	text := strings.ToLower(strings.TrimSpace(r.Text))
+	switch {
+	case strings.HasPrefix(text, "directory:"), strings.HasPrefix(text, "[dir]"):
+		return EntryDir
+	case strings.HasPrefix(text, "symlink:"), strings.HasPrefix(text, "[link]"), strings.HasPrefix(text, "[symlink]"):
+		return EntrySymlink
+	}
+
+	if strings.HasSuffix(r.URI, "/") {
+		return EntryDir
+	}
+	return EntryFile
+}
+
+// fileURIPath turns a file:// URI into a path. Servers send both proper
+// percent-encoded URIs (file:///docs/My%20Report.pdf) and the raw path glued
+// to "file://" (file:///docs/My Report.pdf), so only a URI without spaces
+// that decodes cleanly is decoded; a raw "100%.txt" stays as it is.
+//
+// Windows forms: file:///C:/x and file://C:\x give C:/x and C:\x,
+// file://server/share/x and file:////server/share/x give the UNC path
+// \\server\share\x.
+func fileURIPath(uri string) (string, bool) {
+	if len(uri) < len("file://") || !strings.EqualFold(uri[:len("file://")], "file://") {
+		return "", false
+	}
+	rest := uri[len("file://"):]
+	if rest == "" {
+		return "", false
+	}
+	if local, ok := strings.CutPrefix(rest, "localhost/"); ok {
+		rest = "/" + local
+	}
 
+	if strings.Contains(rest, "%") && !strings.ContainsAny(rest, " \t") {
+		if decoded, err := url.PathUnescape(rest); err == nil {
+			rest = decoded
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "/") && hasDriveLetter(rest[1:]):
+		rest = rest[1:] // file:///C:/x
+	case strings.HasPrefix(rest, "//") && !strings.HasPrefix(rest, "///"):
+		rest = strings.ReplaceAll(rest, "/", `\`) // file:////server/share
+	case !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, `\`) && !hasDriveLetter(rest):
+		rest = `\\` + strings.ReplaceAll(rest, "/", `\`) // file://server/share
+	}
+
+	if len(rest) > 1 && !(hasDriveLetter(rest) && len(rest) == 3) {
+		rest = strings.TrimRight(rest, `/\`)
+	}
+	return rest, true
 }
@@ -4,198 +4,599 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 var filterConfig FilterConfig
 var Filtertypestr string
+var typesPath string
 
 var deleteMode bool
 var undoMode bool
 var historyMode bool
 
+var undoIndexes string
+var undoMatch string
+var undoSince string
+var undoSession string
+
+var recursiveMode bool
+var maxDepth int
+var workers int
+
+var unusedDays int
+var ageBasisStr string
+var ageBasis AgeBasis
+
+var duplicatesMode bool
+var keepPolicyStr string
+var keepPolicy KeepPolicy
+
+var rulesSpec string
+var sniffContent bool
+var usageMode bool
+var usageTop int
+var browseMode bool
+
+var outputFormat string
+var outputPath string
+
+var serverConfigPath string
+var serverCommand string
+var serverArgs stringList
+var serverEnv stringList
+var serverDir string
+var serverRoots stringList
+var serverURL string
+var serverToken string
+var serverHeaders stringList
+var serverLogPath string
+var maxRestarts int
+
 func init() {
-	flag.StringVar(&filterConfig.ExcludePattern, "exclude", "e", "Exclude files matching pattern")
-	flag.StringVar(&filterConfig.IncludePattern, "include", "i", "Include only files matching pattern")
+	flag.Var(&filterConfig.ExcludePatterns, "exclude", "Skip paths matching a gitignore-style glob or re:<regexp>, repeatable")
+	flag.Var(&filterConfig.IncludePatterns, "include", "Only report files matching a gitignore-style glob or re:<regexp>, repeatable")
 	flag.Int64Var(&filterConfig.MinSizeMB, "min-size", 0, "Minimum file size in MB")
 	flag.Int64Var(&filterConfig.MaxSizeMB, "max-size", 0, "Maximum file size in MB")
 
-	flag.StringVar(&Filtertypestr, "filter", "f", "Filter by type (all, pdf, doc, img, code, archive)")
+	flag.StringVar(&Filtertypestr, "filter", "all", "Comma-separated file types to report, e.g. pdf,image (see --types)")
+	flag.StringVar(&typesPath, "types", "", "JSON or YAML file extending the file type catalog (default: "+DefaultTypeCatalogPath()+" if it exists)")
 
 	// Deletion flags
 	flag.BoolVar(&deleteMode, "delete", false, "Enable safe file deletion mode")
 	flag.BoolVar(&undoMode, "undo", false, "Undo last file deletion")
 	flag.BoolVar(&historyMode, "history", false, "Show deletion history")
+	flag.StringVar(&undoIndexes, "undo-index", "", "Restore history entries by number, e.g. 3,7,10-12 (see --history)")
+	flag.StringVar(&undoMatch, "undo-match", "", "Restore files whose name or original path matches a glob, e.g. '*.pdf'")
+	flag.StringVar(&undoSince, "undo-since", "", "Restore files deleted at or after a time, e.g. '2026-01-18 15:00'")
+	flag.StringVar(&undoSession, "undo-session", "", "Restore every file of a deletion session (ID from --history, or 'last')")
+
+	// Scan flags
+	flag.BoolVar(&recursiveMode, "recursive", false, "Scan subdirectories too")
+	flag.IntVar(&maxDepth, "max-depth", 0, "Maximum directory depth for --recursive (0 = no limit)")
+	flag.IntVar(&workers, "workers", 8, "Number of listing and file info requests sent to the MCP server at once")
+
+	// Rule flags
+	flag.IntVar(&unusedDays, "unused-days", 60, "Flag files older than this many days as unused")
+	flag.StringVar(&ageBasisStr, "age-basis", string(AgeMtime), "Timestamp that decides a file's age (atime, mtime, ctime, newest)")
+	flag.StringVar(&rulesSpec, "rules", "", "Comma-separated rules to run, or 'all'")
+	flag.BoolVar(&duplicatesMode, "duplicates", false, "Find duplicate files by content hash (same as adding 'duplicate' to --rules)")
+	flag.StringVar(&keepPolicyStr, "keep", string(KeepNewest), "Which duplicate copy to keep (newest, oldest)")
+	flag.BoolVar(&sniffContent, "sniff", false, "Read the first bytes of every file to detect its type from the content")
+
+	// Report flags
+	flag.StringVar(&outputFormat, "format", FormatText, "Report format (text, json, csv, ndjson)")
+	flag.StringVar(&outputPath, "output", "", "Write the report to a file instead of stdout")
+	flag.BoolVar(&usageMode, "usage", false, "Add disk usage: largest files and directories, space by file type")
+	flag.IntVar(&usageTop, "top", defaultUsageTop, "How many files and directories --usage lists (0 = all)")
+	flag.BoolVar(&browseMode, "browse", false, "Browse the results in a full-screen view and move selected files to the trash")
+
+	// MCP server flags, these override the config file
+	flag.StringVar(&serverConfigPath, "server-config", "", "JSON file describing the MCP server (default: "+DefaultServerConfigPath()+" if it exists)")
+	flag.StringVar(&serverCommand, "server", "", "MCP server executable (default "+defaultServerCommand+")")
+	flag.Var(&serverArgs, "server-arg", "Extra argument for the MCP server, repeatable")
+	flag.Var(&serverEnv, "server-env", "KEY=VALUE environment variable for the MCP server, repeatable")
+	flag.StringVar(&serverDir, "server-dir", "", "Working directory for the MCP server")
+	flag.Var(&serverRoots, "root", "Extra directory the MCP server may access, repeatable (scan paths are always allowed)")
+	flag.StringVar(&serverURL, "server-url", "", "URL of a remote MCP server (Streamable HTTP) instead of starting one")
+	flag.StringVar(&serverToken, "server-token", "", "Bearer token for --server-url")
+	flag.Var(&serverHeaders, "server-header", "'Name: value' HTTP header for --server-url, repeatable")
+	flag.StringVar(&serverLogPath, "server-log", DefaultServerLogPath(), "File the MCP server's stderr is appended to ('' = don't keep it)")
+	flag.IntVar(&maxRestarts, "max-restarts", defaultMaxRestarts, "How often a crashed MCP server is restarted before giving up")
 
 }
 
+// Exit codes, so cron jobs and CI can tell a clean tree from one with findings
+const (
+	ExitClean    = 0 // scan finished, nothing flagged
+	ExitFindings = 1 // scan finished, at least one file flagged
+	ExitError    = 2 // bad usage, connection failure or a path that couldn't be scanned
+)
+
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [path ...]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Scans every path given. Without paths it asks for one (interactive terminals only).\n\n")
+		flag.PrintDefaults()
+	}
+	// the registry is filled by init() in other files, so describe the
+	// rules here rather than in init()
+	flag.Lookup("rules").Usage += ": " + ruleHelp()
 	flag.Parse()
 
+	os.Exit(run())
+}
+
+func run() int {
 	if historyMode {
-		handleHistoryMode()
-		return
+		return handleHistoryMode()
+	}
+
+	if undoIndexes != "" || undoMatch != "" || undoSince != "" || undoSession != "" {
+		return handleSelectiveUndo()
 	}
 
 	if undoMode {
-		handleUndoMode()
-		return
+		return handleUndoMode()
+	}
+
+	catalog, err := resolveTypeCatalog()
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	typeCatalog = catalog
+
+	filterConfig.Categories, err = typeCatalog.ParseFilter(Filtertypestr)
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+
+	if err := filterConfig.CompilePatterns(); err != nil {
+		PrintError(err.Error())
+		return ExitError
 	}
 
-	filterConfig.FileType = FILTERALL
+	basis, err := parseAgeBasis(ageBasisStr)
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	ageBasis = basis
 
-	filterConfig.FileType = parserFilterType(Filtertypestr)
+	keep, err := parseKeepPolicy(keepPolicyStr)
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	keepPolicy = keep
+
+	if usageTop < 0 {
+		PrintError(fmt.Sprintf("--top must not be negative, got %d", usageTop))
+		return ExitError
+	}
+
+	if browseMode && (!isTerminal(os.Stdin) || !isTerminal(os.Stdout)) {
+		PrintError("--browse needs an interactive terminal")
+		return ExitError
+	}
+	if browseMode && deleteMode {
+		PrintError("--browse and --delete can't be combined (the browser trashes files itself)")
+		return ExitError
+	}
+
+	serverConfig, err := resolveServerConfig()
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	// the trash is on this machine, a server's path could name any local file
+	if serverConfig.URL != "" && (deleteMode || browseMode) {
+		PrintError("--delete and --browse only work with a local server, not --server-url")
+		return ExitError
+	}
+
+	if workers < 1 {
+		PrintError(fmt.Sprintf("--workers must be at least 1, got %d", workers))
+		return ExitError
+	}
+
+	if duplicatesMode {
+		if rulesSpec == "" {
+			rulesSpec = strings.Join(defaultRuleIDs(), ",")
+		}
+		rulesSpec += ",duplicate"
+	}
+	rules, err := BuildRules(rulesSpec)
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	if hasRule(rules, "extension-mismatch") {
+		sniffContent = true
+	}
+
+	if !validFormat(outputFormat) {
+		PrintError("Unknown --format " + outputFormat + " (use text, json, csv or ndjson)")
+		return ExitError
+	}
+	if usageMode && outputFormat == FormatCSV {
+		PrintError("--usage has no place in a csv report (one row per finding), use --format json or ndjson")
+		return ExitError
+	}
+
+	out := io.Writer(os.Stdout)
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			PrintError("Failed to create output file: " + err.Error())
+			return ExitError
+		}
+		defer f.Close()
+		out = f
+	}
+
+	// No colors in files or machine-readable output, and no decorations
+	// mixed into a json/csv/ndjson report
+	if outputFormat != FormatText || outputPath != "" {
+		disableColors()
+	}
+	if outputFormat == FormatText {
+		tuiOut = out
+	} else {
+		tuiOut = io.Discard
+		msgOut = os.Stderr
+	}
 
 	// PrintLogo()
 	PrintHeader("Filesystem Analyzer v2.0")
 
-	reader := bufio.NewReader(os.Stdin)
+	paths := flag.Args()
+	if len(paths) == 0 {
+		if !isTerminal(os.Stdin) {
+			PrintError("No path given (pass one or more paths as arguments)")
+			return ExitError
+		}
 
-	PrintSection("Input")
-	fmt.Print("  Enter directory path to analyze: ")
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		PrintError("Failed to read input: " + err.Error())
-		return
+		reader := bufio.NewReader(os.Stdin)
+
+		PrintSection("Input")
+		fmt.Fprint(msgOut, "  Enter directory path to analyze: ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			PrintError("Failed to read input: " + err.Error())
+			return ExitError
+		}
+		paths = []string{strings.TrimSpace(input)}
 	}
 
-	desiredpath := strings.TrimSpace(input)
+	// paths on a remote server are the server's, not ours
+	if serverConfig.URL == "" {
+		for i, p := range paths {
+			if abs, err := filepath.Abs(p); err == nil {
+				paths[i] = abs
+			}
+		}
+	}
 
 	PrintSection("Filter Settings")
-	fmt.Printf("  Filter: %s%s%s\n", ColorYellow+ColorBold, filterConfig.FileType.String(), ColorReset)
-	fmt.Printf("  Include: %s%s%s\n", ColorDim, filterConfig.IncludePattern, ColorReset)
-	fmt.Printf("  Exclude: %s%s%s\n", ColorDim, filterConfig.ExcludePattern, ColorReset)
-	fmt.Printf("  Min Size: %d MB%s\n", filterConfig.MinSizeMB, ColorReset)
-	fmt.Printf("  Max Size: %d MB%s\n", filterConfig.MaxSizeMB, ColorReset)
+	fmt.Fprintf(tuiOut, "  Filter: %s%s%s\n", ColorYellow+ColorBold, filterConfig.FilterLabel(), ColorReset)
+	fmt.Fprintf(tuiOut, "  Include: %s%s%s\n", ColorDim, filterConfig.include, ColorReset)
+	fmt.Fprintf(tuiOut, "  Exclude: %s%s%s\n", ColorDim, filterConfig.exclude, ColorReset)
+	fmt.Fprintf(tuiOut, "  Ignore Files: %s%s in every scanned directory%s\n", ColorDim, ignoreFileName, ColorReset)
+	fmt.Fprintf(tuiOut, "  Min Size: %d MB%s\n", filterConfig.MinSizeMB, ColorReset)
+	fmt.Fprintf(tuiOut, "  Max Size: %d MB%s\n", filterConfig.MaxSizeMB, ColorReset)
+	fmt.Fprintf(tuiOut, "  Unused After: %d days (%s)%s\n", unusedDays, ageBasis, ColorReset)
+	fmt.Fprintf(tuiOut, "  Rules: %s%s%s\n", ColorDim, strings.Join(activeRuleIDs(rules), ", "), ColorReset)
 
 	PrintSection("Connecting to MCP Server")
-	PrintSuccess("Starting mcp-filesystem-server...")
+	if serverConfig.URL != "" {
+		PrintSuccess("Connecting to " + serverConfig.String() + "...")
+	} else {
+		PrintSuccess("Starting " + serverConfig.String() + "...")
+	}
 
-	client, err := NewMCPClient(desiredpath)
+	client, err := NewMCPClient(serverConfig, paths)
 	if err != nil {
 		PrintError("Failed to connect: " + err.Error())
-		return
+		return ExitError
+	}
+	defer client.Close()
+	client.MaxRestarts = maxRestarts
+	client.OnRestart = func(cause error, attempt int) {
+		PrintWarning(fmt.Sprintf("%v, restarting it (%d/%d)", cause, attempt, maxRestarts))
+	}
+	if err := client.RequireTools(requiredTools...); err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	PrintSuccess(fmt.Sprintf("Connected to %s (MCP %s, %d tools)", client.serverName(), client.ProtocolVersion, len(client.Tools)))
+	if client.Remote && (hasRule(rules, "duplicate") || sniffContent) {
+		PrintWarning("Duplicates and content types need the file content, which isn't read from a remote server; they are skipped")
 	}
-	PrintSuccess("Connected!")
 
-	PrintSection("Scanning Directory")
-	PrintFileInfo("Path", desiredpath)
+	// Scan every path in turn, results end up in one combined report
+	var files []*FileInfo
+	scanErrors := 0
+	failedRoots := 0
 
-	files, err := list_directory(client, desiredpath)
-	if err != nil {
-		PrintError("Failed to scan: " + err.Error())
-		return
+	for _, desiredpath := range paths {
+		PrintSection("Scanning Directory")
+		PrintFileInfo("Path", desiredpath)
+
+		if ageBasis.usesAtime() && serverConfig.URL == "" {
+			if reliable, why := atimeReliability(desiredpath); !reliable {
+				PrintWarning("Access times may be stale: " + why)
+			}
+		}
+
+		scan := walk_directory(client, desiredpath, recursiveMode, maxDepth, workers, filterConfig.exclude)
+		for _, cycle := range scan.Cycles {
+			PrintWarning("Skipped already visited directory (link cycle): " + cycle)
+		}
+		for _, link := range scan.Unfollowed {
+			PrintWarning("Not following symlink on the server (loops can't be detected there): " + link)
+		}
+		for _, scanErr := range scan.Errors {
+			PrintWarning(fmt.Sprintf("Could not read %s: %v", scanErr.Path, scanErr.Err))
+		}
+		PrintParseWarnings(scan.Files)
+		scanErrors += len(scan.Errors)
+		if len(scan.Files) == 0 && len(scan.Errors) > 0 {
+			PrintError("Failed to scan: " + desiredpath)
+			failedRoots++
+			continue
+		}
+
+		for _, info := range scan.Files {
+			if !info.IsDirectory {
+				files = append(files, info)
+			}
+		}
 	}
 
 	PrintFileCount(len(files))
+
+	// Apply filters
+	matched := filterFiles(files)
+
+	PrepareRules(rules, matched)
+
 	if deleteMode {
-		handleDeleteMode(files, client)
-		return
+		return handleDeleteMode(matched, rules)
+	}
+
+	if browseMode {
+		return handleBrowseMode(matched, rules)
 	}
 
-	unusedCount := 0
-	zeroByteCount := 0
+	report := &Report{GeneratedAt: time.Now(), Roots: paths}
+	counts := map[string]int{}
+	for _, rule := range rules {
+		counts[rule.ID()] = 0
+	}
 	matchedCount := 0
 
-	for _, f := range files {
-		info, err := GetFileInfo(client, f)
-		if err != nil {
-			continue
-		}
-		// Apply filters
-		if !ShouldInclude(f, filterConfig) {
-			continue
+	for _, info := range matched {
+		findings := EvaluateRules(rules, info)
+		for _, f := range findings {
+			PrintFinding(f.Rule, info.Path, f.Explanation)
+			counts[f.Rule.ID()]++
 		}
 
-		if !ShouldIncludeSize(f, filterConfig, info.SizeBytes) {
-			continue
+		if len(findings) > 0 {
+			report.AddFile(info, findings)
 		}
+		matchedCount++
+	}
+	reclaimable, reclaimableTotal := report.Reclaimable(rules)
 
-		if exp := ExplainUnused(info, 60); exp != nil {
-			PrintUnusedFile(info.Path, exp.Evidence)
-			unusedCount++
+	for _, rule := range rules {
+		if s, ok := rule.(Summarizer); ok {
+			s.Summarize(report)
 		}
+	}
 
-		if expzero := ExplainZeroByte(info); expzero != nil {
-			PrintZeroByteFile(info.Path, expzero.Reason, expzero.Evidence)
-			zeroByteCount++
-		}
-		matchedCount++
+	if usageMode {
+		report.Usage = BuildUsage(matched, usageTop)
+		PrintUsage(report.Usage)
 	}
+
 	PrintDivider()
-	fmt.Printf("%sFiles Matching Filter:%s %d%s\n",
+	fmt.Fprintf(tuiOut, "%sFiles Matching Filter:%s %d%s\n",
 		ColorYellow+ColorBold,
 		ColorReset,
 		matchedCount,
 		ColorReset)
 	PrintDivider()
 
-	PrintScanComplete(len(files), unusedCount, zeroByteCount)
+	report.Summary = ReportSummary{
+		FilesScanned:   len(files),
+		FilesMatched:   matchedCount,
+		FilesFlagged:   len(report.Files),
+		ScanErrors:     scanErrors,
+		FindingsByRule: counts,
+
+		ReclaimableBytesByRule: reclaimable,
+		ReclaimableBytes:       reclaimableTotal,
+	}
+
+	if outputFormat == FormatText {
+		PrintScanComplete(rules, report.Summary)
+	} else if err := WriteReport(out, outputFormat, report); err != nil {
+		PrintError("Failed to write report: " + err.Error())
+		return ExitError
+	}
+
+	switch {
+	case failedRoots > 0:
+		return ExitError
+	case len(report.Files) > 0:
+		return ExitFindings
+	default:
+		return ExitClean
+	}
 }
 
-func handleHistoryMode() {
+func handleHistoryMode() int {
 	PrintHeader("Deletion History")
 
 	history, err := LoadHistory(GetHistoryFilePath())
 	if err != nil {
 		PrintError("Failed to load history: " + err.Error())
-		return
+		return ExitError
 	}
 
 	ShowHistory(history)
+	return ExitClean
 }
 
-func handleUndoMode() {
+func handleUndoMode() int {
 	PrintHeader("Undo Last Deletion")
 
 	history, err := LoadHistory(GetHistoryFilePath())
 	if err != nil {
 		PrintError("Failed to load history: " + err.Error())
-		return
+		return ExitError
 	}
 
 	if err := UndoLastDeletion(history); err != nil {
 		PrintError("Failed to undo: " + err.Error())
-		return
+		return ExitError
 	}
 
 	// Save updated history
 	if err := SaveHistory(history, GetHistoryFilePath()); err != nil {
 		PrintError("Failed to save history: " + err.Error())
-		return
+		return ExitError
 	}
 
 	PrintSuccess("Undo completed successfully!")
+	return ExitClean
 }
 
-func handleDeleteMode(files []string, client *MCPClient) {
+func handleSelectiveUndo() int {
+	PrintHeader("Restore Deleted Files")
+
+	sel := RestoreSelector{Glob: undoMatch, Session: undoSession}
+
+	if undoSince != "" {
+		since, err := parseSinceTime(undoSince)
+		if err != nil {
+			PrintError(err.Error())
+			return ExitError
+		}
+		sel.Since = since
+	}
+
+	history, err := LoadHistory(GetHistoryFilePath())
+	if err != nil {
+		PrintError("Failed to load history: " + err.Error())
+		return ExitError
+	}
+
+	// the history's length bounds the ranges
+	if undoIndexes != "" {
+		indexes, err := parseIndexList(undoIndexes, len(history.Records))
+		if err != nil {
+			PrintError(err.Error())
+			return ExitError
+		}
+		sel.Indexes = indexes
+	}
+
+	selected, err := SelectRecords(history, sel)
+	if err != nil {
+		PrintError(err.Error())
+		return ExitError
+	}
+	if len(selected) == 0 {
+		PrintInfo("No deleted files match")
+		return ExitClean
+	}
+
+	results := RestoreRecords(history, selected)
+
+	restoredCount := 0
+	for _, r := range results {
+		if r.Err != nil {
+			PrintError(fmt.Sprintf("%s: %v", r.Record.OrigionalFilePath, r.Err))
+			continue
+		}
+		PrintSuccess("Restored " + r.Record.OrigionalFilePath)
+		restoredCount++
+	}
+
+	if restoredCount > 0 {
+		if err := SaveHistory(history, GetHistoryFilePath()); err != nil {
+			PrintError("Failed to save history: " + err.Error())
+			return ExitError
+		}
+	}
+
+	PrintDivider()
+	fmt.Printf("Files restored: %s%d%s\n", ColorYellow+ColorBold, restoredCount, ColorReset)
+	fmt.Printf("Files failed: %s%d%s\n", ColorDim, len(results)-restoredCount, ColorReset)
+	PrintDivider()
+
+	if restoredCount < len(results) {
+		return ExitError
+	}
+	return ExitClean
+}
+
+// filterFiles applies the type, --include and size filters.
+// --exclude and .analyzerignore were already applied by the walk.
+func filterFiles(files []*FileInfo) []*FileInfo {
+	var matched []*FileInfo
+	for _, info := range files {
+		if !ShouldInclude(info, filterConfig) {
+			continue
+		}
+
+		if !ShouldIncludePath(info.RelPath, filterConfig) {
+			continue
+		}
+
+		if !ShouldIncludeSize(info.Path, filterConfig, info.SizeBytes) {
+			continue
+		}
+		matched = append(matched, info)
+	}
+	return matched
+}
+
+// handleDeleteMode offers the (already filtered) files for deletion one by one.
+// With the duplicate rule active only the redundant copies are offered, never the keeper.
+func handleDeleteMode(files []*FileInfo, rules []Rule) int {
 	PrintHeader("Safe File Deletion Mode")
-	PrintWarning("This will move files to the Recycle Bin - you can restore them later!")
+	PrintWarning("This will move files to the " + trashBackend.Name() + " - you can restore them later!")
 
 	// Load existing history
 	history, err := LoadHistory(GetHistoryFilePath())
 	if err != nil {
 		PrintError("Failed to load history: " + err.Error())
-		return
+		return ExitError
 	}
 
+	sessionID := newSessionID()
 	deletedCount := 0
 	skippedCount := 0
 
-	for _, f := range files {
-		// Apply filters
-		if !ShouldInclude(f, filterConfig) {
-			continue
-		}
-
-		info, err := GetFileInfo(client, f)
-		if err != nil {
+	for _, info := range files {
+		findings := EvaluateRules(rules, info)
+		if hasRule(rules, "duplicate") && !hasFinding(findings, "duplicate") {
 			continue
 		}
 
-		// Check size filter
-		if !ShouldIncludeSize(f, filterConfig, info.SizeBytes) {
+		// never delete something other than what the server listed
+		if !info.ListedVerbatim() {
+			PrintWarning("Not offering " + info.Path + " for deletion: " + info.PathCorrection)
 			continue
 		}
 
@@ -203,26 +604,22 @@ func handleDeleteMode(files []string, client *MCPClient) {
 		fmt.Printf("\n"+ColorCyan+"File %d:"+ColorReset+"\n", deletedCount+skippedCount+1)
 		fmt.Printf("Name: %s\n", getFileName(info.Path))
 		fmt.Printf("Size: %s\n", formatFileSize(info.SizeBytes))
-		fmt.Printf("Type: %s\n", getFileType(info.Path))
+		fmt.Printf("Type: %s\n", getFileType(info))
 		fmt.Printf("Path: %s\n", info.Path)
 
-		// Check if it's unused or zero-byte
-		if exp := ExplainUnused(info, 60); exp != nil {
-			fmt.Printf(ColorRed+"⚠ Unused: %s"+ColorReset+"\n", exp.Evidence)
-		}
-
-		if expzero := ExplainZeroByte(info); expzero != nil {
-			fmt.Printf(ColorRed+"⚠ Zero-byte: %s"+ColorReset+"\n", expzero.Reason)
+		// Show why the rules flagged it
+		for _, f := range findings {
+			fmt.Printf(severityColor(f.Rule.Severity())+"⚠ %s: %s"+ColorReset+"\n", ruleTitle(f.Rule.ID()), findingSummary(f.Explanation))
 		}
 
 		// Ask for confirmation
 		if ConfirmDeletion(*info) {
-			if err := DeleteFile(*info, history); err != nil {
+			if err := DeleteFile(*info, history, sessionID); err != nil {
 				PrintError("Failed to delete file: " + err.Error())
 				skippedCount++
 				continue
 			}
-			PrintSuccess("File moved to Recycle Bin!")
+			PrintSuccess("File moved to " + trashBackend.Name() + "!")
 			deletedCount++
 		} else {
 			PrintInfo("Skipped")
@@ -231,7 +628,7 @@ func handleDeleteMode(files []string, client *MCPClient) {
 
 		// Ask if user wants to continue
 		if deletedCount+skippedCount > 0 {
-			fmt.Printf("\n" + ColorYellow + "Continue? (Y/n): " + ColorReset)
+			fmt.Print("\n" + ColorYellow + "Continue? (Y/n): " + ColorReset)
 			var response string
 			fmt.Scanln(&response)
 			if response == "n" || response == "N" {
@@ -251,11 +648,27 @@ func handleDeleteMode(files []string, client *MCPClient) {
 
 	// Show summary
 	PrintDivider()
-	fmt.Printf(ColorGreen + "Deletion Complete!" + ColorReset + "\n")
+	fmt.Print(ColorGreen + "Deletion Complete!" + ColorReset + "\n")
 	fmt.Printf("Files deleted: %s%d%s\n", ColorYellow+ColorBold, deletedCount, ColorReset)
 	fmt.Printf("Files skipped: %s%d%s\n", ColorDim, skippedCount, ColorReset)
 	PrintDivider()
 
 	PrintInfo("Use --history to see deleted files")
 	PrintInfo("Use --undo to restore the last deleted file")
+	if deletedCount > 0 {
+		PrintInfo("Use --undo-session " + sessionID + " to restore everything deleted in this session")
+	}
+	return ExitClean
+}
+
+// stringList is a flag that can be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
@@ -1,80 +1,451 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"os/exec"
-	"bufio"
+	"sync"
+	"time"
 )
 
+// defaultCallTimeout bounds ToolCall, a stuck server must not hang the scan
+const defaultCallTimeout = 30 * time.Second
+
+// defaultMaxRestarts is how often a crashed server is restarted per scan
+const defaultMaxRestarts = 3
+
+// ErrClientClosed is returned for calls made after the server's stdout
+// ended (server exited or crashed)
+var ErrClientClosed = errors.New("MCP server connection closed")
+
+// MCPClient is a JSON-RPC 2.0 client for an MCP server.
+//
+// The protocol work happens on an rpcConn; MCPClient adds the handshake
+// results and, for servers it started itself, restarts after a crash.
+// It is safe for concurrent use.
 type MCPClient struct {
-	cmd    *exec.Cmd
-	stdin  any
-	stdout any
-	nextID int
+	mu   sync.Mutex
+	conn *rpcConn
+
+	// reconnect starts a fresh server and runs the handshake on it.
+	// nil when the client can't restart its server.
+	reconnect func(ctx context.Context) (*rpcConn, error)
+	restartMu sync.Mutex // one restart at a time
+	restarts  int
+
+	// MaxRestarts limits crash restarts over the client's lifetime
+	MaxRestarts int
+
+	// Timeout bounds ToolCall (0 = defaultCallTimeout)
+	Timeout time.Duration
+
+	// OnNotification, if set, is called from the reader goroutine for
+	// every notification the server sends
+	OnNotification func(method string, params json.RawMessage)
+
+	// OnRestart, if set, is told about every restart after a crash
+	OnRestart func(cause error, attempt int)
+
+	// Remote is set for a --server-url server, its paths aren't on this
+	// machine and nothing may be read or stat'ed locally under them
+	Remote bool
+
+	// Filled in by Initialize
+	ProtocolVersion string
+	Server          MCPServerInfo
+	Capabilities    json.RawMessage
+	Tools           map[string]MCPTool
 }
 
-func NewMCPClient(allowedpath string) (*MCPClient, error) {
-	cmd := exec.Command("mcp-filesystem-server", allowedpath)
+// NewMCPClient connects to the configured MCP server and runs the MCP
+// handshake. Local servers are started with the configured roots and every
+// scan path as allowed directories, and restarted if they crash; with
+// cfg.URL set the server is remote and reached over Streamable HTTP.
+func NewMCPClient(cfg *ServerConfig, scanPaths []string) (*MCPClient, error) {
+	client := &MCPClient{MaxRestarts: defaultMaxRestarts, Remote: cfg.URL != ""}
 
-	stdin, _ := cmd.StdinPipe()
-	stdout, _ := cmd.StdoutPipe()
+	if cfg.URL != "" {
+		client.conn = client.newConn(newHTTPTransport(cfg.URL, cfg.httpHeaders(), nil), nil)
+	} else {
+		conn, err := client.startServer(cfg, scanPaths)
+		if err != nil {
+			return nil, err
+		}
+		client.conn = conn
 
-	err := cmd.Start()
-	if err != nil {
-		panic(err)
+		client.reconnect = func(ctx context.Context) (*rpcConn, error) {
+			conn, err := client.startServer(cfg, scanPaths)
+			if err != nil {
+				return nil, err
+			}
+			if err := client.initialize(ctx, conn); err != nil {
+				conn.close()
+				return nil, err
+			}
+			return conn, nil
+		}
 	}
-	return &MCPClient{
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: stdout,
-		nextID: 1,
-	}, nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := client.Initialize(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// newClientConn runs the JSON-RPC protocol over any reader/writer pair,
+// e.g. io.Pipes connected to an in-process fake server
+func newClientConn(r io.Reader, w io.Writer) *MCPClient {
+	return newClient(newStdioTransport(r, w))
+}
+
+func newClient(t Transport) *MCPClient {
+	c := &MCPClient{}
+	c.conn = c.newConn(t, nil)
+	return c
+}
+
+// current is the connection calls should use right now
+func (c *MCPClient) current() *rpcConn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+// Close shuts the server down, see rpcConn.close
+func (c *MCPClient) Close() error {
+	return c.current().close()
 }
 
-//NOTE:ToolCall is a function or method of struct MCPClient
+// Call sends a JSON-RPC request and returns its result.
+// A JSON-RPC error from the server comes back as *RPCError.
+func (c *MCPClient) Call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	return c.current().callResult(ctx, method, params)
+}
+
+// Notify sends a notification (a request without an ID, no answer expected)
+func (c *MCPClient) Notify(method string, params any) error {
+	return c.current().notify(method, params)
+}
 
+// ToolCall runs an MCP tool with the client's default timeout and returns
+// the full response line, to be unmarshalled into MCPResponse
 func (c *MCPClient) ToolCall(name string, params map[string]any) ([]byte, error) {
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextID,
-		"method":  "tools/call",
-		"params": map[string]any{ //function name and arguments are passed
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultCallTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.ToolCallContext(ctx, name, params)
+}
+
+// ToolCallContext is ToolCall with a caller-supplied context.
+// If the server crashes during the call it is restarted and the call is
+// retried, as long as the restart budget lasts. Our tools only read, so
+// running one twice is harmless.
+func (c *MCPClient) ToolCallContext(ctx context.Context, name string, params map[string]any) ([]byte, error) {
+	for {
+		conn := c.current()
+		msg, err := conn.call(ctx, "tools/call", map[string]any{ //function name and arguments are passed
 			"name":      name,
 			"arguments": params,
-		},
+		})
+		if err == nil {
+			return msg.raw, nil
+		}
+		// the server answered, so it's alive; only a broken connection
+		// is worth waiting for and restarting
+		var rpcErr *RPCError
+		if ctx.Err() != nil || errors.As(err, &rpcErr) || !conn.lost() {
+			return nil, err
+		}
+		if err := c.restart(ctx, conn, err); err != nil {
+			return nil, err
+		}
 	}
-	c.nextID++
+}
 
-	// Fixed : Add type assertion for io.Writer
-	json.NewEncoder(c.stdin.(io.Writer)).Encode(request)
-	// Fixed : Add type assertion for io.WriteCloser
+// restart replaces the dead connection with a fresh server. Calls that
+// failed on the same connection wait here and then all use the new one.
+func (c *MCPClient) restart(ctx context.Context, dead *rpcConn, cause error) error {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
 
-	// c.stdin.(io.WriteCloser).Close() //tells the mcp no more request
+	if c.current() != dead {
+		return nil // another call already restarted it
+	}
+	if c.reconnect == nil {
+		return cause
+	}
+	if c.restarts >= c.MaxRestarts {
+		return fmt.Errorf("%v (gave up after %d restarts)", cause, c.restarts)
+	}
+	c.restarts++
+	if c.OnRestart != nil {
+		c.OnRestart(cause, c.restarts)
+	}
 
-	//  READ THE FULL RESPONSE FROM THE MCP SERVER
-	// buf := make([]byte, 1024) //data comes in chunks
-	out := []byte{}
+	dead.close()
+	conn, err := c.reconnect(ctx)
+	if err != nil {
+		return fmt.Errorf("%v; restarting the server failed: %v", cause, err)
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	return nil
+}
+
+// rpcMessage is any JSON-RPC message the server can send:
+// a response (ID + Result/Error), a notification (Method, no ID) or a
+// request to the client (Method + ID)
+type rpcMessage struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+
+	raw []byte // the whole message, for callers that unmarshal it themselves
+}
 
-	// for {
-	// 	n, err := c.stdout.(io.Reader).Read(buf)
-	// 	if n > 0 {
-	// 		out = append(out, buf[:n]...)
-	// 	}
-	// 	if err != nil {
-	// 		break
-	// 	}
-	// }
-	scanner := bufio.NewScanner(c.stdout.(io.Reader))
-	scanner.Buffer(make([]byte, 1024), 1024*1024)
-	if scanner.Scan() {
-		out = append(out, scanner.Bytes()...)
+// err is the response's JSON-RPC error as a Go error (nil on success)
+func (m rpcMessage) err() error {
+	if m.Error != nil {
+		return m.Error
 	}
+	return nil
+}
+
+// rpcConn is one connection to one server (process).
+//
+// One reader goroutine takes every message the transport receives and
+// hands each response to the call waiting for its ID, so several calls can
+// be in flight at once.
+type rpcConn struct {
+	client    *MCPClient
+	transport Transport
+	proc      *serverProcess // nil unless we started the server
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan rpcMessage
+	done    chan struct{} // closed when the reader stops
+	readErr error         // why the reader stopped
+}
 
-	if err := scanner.Err(); err != nil {
+func (c *MCPClient) newConn(t Transport, proc *serverProcess) *rpcConn {
+	conn := &rpcConn{
+		client:    c,
+		transport: t,
+		proc:      proc,
+		nextID:    1,
+		pending:   map[int]chan rpcMessage{},
+		done:      make(chan struct{}),
+	}
+	go conn.readLoop()
+	return conn
+}
+
+// readLoop dispatches server messages until the transport closes
+func (c *rpcConn) readLoop() {
+	for data := range c.transport.Messages() {
+		var batch []rpcMessage
+		if data[0] == '[' {
+			// a JSON-RPC batch, each element is handled on its own
+			if err := json.Unmarshal(data, &batch); err != nil {
+				continue
+			}
+			for i := range batch {
+				batch[i].raw, _ = json.Marshal(batch[i])
+			}
+		} else {
+			var msg rpcMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			msg.raw = data
+			batch = []rpcMessage{msg}
+		}
+
+		for _, msg := range batch {
+			switch {
+			case msg.Method != "" && len(msg.ID) == 0:
+				if c.client.OnNotification != nil {
+					c.client.OnNotification(msg.Method, msg.Params)
+				}
+			case msg.Method != "":
+				c.answerServerRequest(msg)
+			default:
+				c.deliver(msg)
+			}
+		}
+	}
+
+	err := c.transport.Err()
+	if c.proc != nil {
+		// stdout ended, so the server exited or is exiting
+		if exitErr := c.proc.wait(); exitErr != nil {
+			err = exitErr
+		}
+	}
+	if err == nil {
+		err = ErrClientClosed
+	}
+
+	c.mu.Lock()
+	c.readErr = err
+	c.pending = map[int]chan rpcMessage{}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// lost reports whether the connection is gone for good (server crashed).
+// A failed write to a dying server can come back before the reader has
+// noticed, so give it a moment.
+func (c *rpcConn) lost() bool {
+	select {
+	case <-c.done:
+		return true
+	case <-time.After(200 * time.Millisecond):
+		return false
+	}
+}
+
+// deliver hands a response to the call waiting for its ID.
+// Responses nobody waits for (the call timed out) are dropped.
+func (c *rpcConn) deliver(msg rpcMessage) {
+	var id int
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	ch, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+
+	if ok {
+		ch <- msg // buffered, never blocks
+	}
+}
+
+// answerServerRequest replies to requests the server sends us.
+// We only answer ping; anything else gets "method not found" so the
+// server isn't left waiting.
+func (c *rpcConn) answerServerRequest(msg rpcMessage) {
+	reply := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+	}
+	if msg.Method == "ping" {
+		reply["result"] = map[string]any{}
+	} else {
+		reply["error"] = &RPCError{Code: RPCMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	// answered from the reader goroutine, so don't wait on it forever
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCallTimeout)
+	defer cancel()
+	_ = c.write(ctx, reply)
+}
+
+// write sends one message through the transport
+func (c *rpcConn) write(ctx context.Context, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// call sends a request and waits for its response, the context's deadline
+// or the server going away, whichever comes first
+func (c *rpcConn) call(ctx context.Context, method string, params any) (rpcMessage, error) {
+	ch := make(chan rpcMessage, 1)
+
+	c.mu.Lock()
+	if c.readErr != nil {
+		err := c.readErr
+		c.mu.Unlock()
+		return rpcMessage{}, err
+	}
+	id := c.nextID
+	c.nextID++
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}
+
+	request := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+	}
+	if params != nil {
+		request["params"] = params
+	}
+	if err := c.write(ctx, request); err != nil {
+		forget()
+		return rpcMessage{}, fmt.Errorf("failed to send %s request: %v", method, err)
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, msg.err()
+	case <-ctx.Done():
+		forget()
+		return rpcMessage{}, fmt.Errorf("%s: %v", method, ctx.Err())
+	case <-c.done:
+		// the answer may have arrived right before the server went away
+		select {
+		case msg := <-ch:
+			return msg, msg.err()
+		default:
+			return rpcMessage{}, c.readErr
+		}
+	}
+}
+
+// callResult is call returning just the result
+func (c *rpcConn) callResult(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	msg, err := c.call(ctx, method, params)
+	if err != nil {
 		return nil, err
 	}
+	return msg.Result, nil
+}
 
-	return out, nil
+// notify sends a notification (no ID, no answer expected)
+func (c *rpcConn) notify(method string, params any) error {
+	msg := map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	return c.write(context.Background(), msg)
+}
 
+// close ends the connection. A server we started sees its stdin close,
+// which MCP servers take as the signal to exit, and is stopped harder if
+// it doesn't (see serverProcess.stop).
+func (c *rpcConn) close() error {
+	err := c.transport.Close()
+	if c.proc != nil {
+		c.proc.stop()
+	}
+	return err
 }
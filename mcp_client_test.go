@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is the server end of two io.Pipes. It hands every message the
+// client sends to the test, which answers with send.
+type fakeServer struct {
+	t        *testing.T
+	out      *io.PipeWriter
+	requests chan rpcMessage
+}
+
+// newFakeServer connects a client to a fake in-process server
+func newFakeServer(t *testing.T) (*MCPClient, *fakeServer) {
+	t.Helper()
+	serverR, clientW := io.Pipe()
+	clientR, serverW := io.Pipe()
+
+	s := &fakeServer{t: t, out: serverW, requests: make(chan rpcMessage, 16)}
+	go func() {
+		scanner := bufio.NewScanner(serverR)
+		for scanner.Scan() {
+			var msg rpcMessage
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				t.Errorf("client sent invalid JSON %q: %v", scanner.Text(), err)
+				continue
+			}
+			s.requests <- msg
+		}
+		close(s.requests)
+	}()
+
+	client := newClientConn(clientR, clientW)
+	t.Cleanup(func() {
+		client.Close()
+		serverW.Close()
+	})
+	return client, s
+}
+
+// next returns the next request the client sent
+func (s *fakeServer) next() rpcMessage {
+	s.t.Helper()
+	select {
+	case msg := <-s.requests:
+		return msg
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("no request from the client")
+		return rpcMessage{}
+	}
+}
+
+// send writes one line to the client
+func (s *fakeServer) send(line string) {
+	s.t.Helper()
+	if _, err := io.WriteString(s.out, line+"\n"); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// reply answers request with result
+func (s *fakeServer) reply(request rpcMessage, result string) {
+	s.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, request.ID, result))
+}
+
+type callResult struct {
+	result json.RawMessage
+	err    error
+}
+
+func goCall(client *MCPClient, method string) <-chan callResult {
+	ch := make(chan callResult, 1)
+	go func() {
+		result, err := client.Call(context.Background(), method, nil)
+		ch <- callResult{result, err}
+	}()
+	return ch
+}
+
+func TestClientMatchesResponsesByID(t *testing.T) {
+	client, server := newFakeServer(t)
+
+	var mu sync.Mutex
+	var notifications []string
+	client.OnNotification = func(method string, params json.RawMessage) {
+		mu.Lock()
+		notifications = append(notifications, method)
+		mu.Unlock()
+	}
+
+	first := goCall(client, "first")
+	firstReq := server.next()
+	second := goCall(client, "second")
+	secondReq := server.next()
+	if string(firstReq.ID) == string(secondReq.ID) {
+		t.Fatalf("both requests have ID %s", firstReq.ID)
+	}
+
+	// answer in reverse order, with the noise servers write in between
+	server.send("Secure MCP Filesystem Server running on stdio")
+	server.send(`{"jsonrpc":"2.0","method":"notifications/message","params":{"level":"info"}}`)
+	server.send("")
+	server.reply(secondReq, `{"n":2}`)
+	server.send("not json either")
+	server.reply(firstReq, `{"n":1}`)
+
+	for _, tt := range []struct {
+		ch   <-chan callResult
+		want string
+	}{{first, `{"n":1}`}, {second, `{"n":2}`}} {
+		select {
+		case r := <-tt.ch:
+			if r.err != nil {
+				t.Fatalf("call failed: %v", r.err)
+			}
+			if string(r.result) != tt.want {
+				t.Errorf("result = %s, want %s", r.result, tt.want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("call didn't return")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notifications) != 1 || notifications[0] != "notifications/message" {
+		t.Errorf("notifications = %v, want [notifications/message]", notifications)
+	}
+}
+
+func TestClientAnswersPing(t *testing.T) {
+	_, server := newFakeServer(t)
+
+	server.send(`{"jsonrpc":"2.0","id":"srv-1","method":"ping"}`)
+	reply := server.next()
+	if string(reply.ID) != `"srv-1"` || reply.Error != nil || string(reply.Result) != "{}" {
+		t.Errorf("ping reply = id %s result %s error %v", reply.ID, reply.Result, reply.Error)
+	}
+
+	server.send(`{"jsonrpc":"2.0","id":7,"method":"sampling/createMessage"}`)
+	reply = server.next()
+	if reply.Error == nil || reply.Error.Code != RPCMethodNotFound {
+		t.Errorf("unknown request reply error = %v, want code %d", reply.Error, RPCMethodNotFound)
+	}
+}
+
+func TestClientReturnsRPCError(t *testing.T) {
+	client, server := newFakeServer(t)
+
+	call := goCall(client, "tools/call")
+	req := server.next()
+	server.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32602,"message":"Invalid params","data":{"path":"/nope"}}}`, req.ID))
+
+	r := <-call
+	var rpcErr *RPCError
+	if !errors.As(r.err, &rpcErr) {
+		t.Fatalf("error = %v (%T), want *RPCError", r.err, r.err)
+	}
+	if rpcErr.Code != -32602 || rpcErr.Message != "Invalid params" {
+		t.Errorf("got code %d message %q", rpcErr.Code, rpcErr.Message)
+	}
+	if string(rpcErr.Data) != `{"path":"/nope"}` {
+		t.Errorf("data = %s", rpcErr.Data)
+	}
+	if want := `MCP error -32602: Invalid params ({"path":"/nope"})`; rpcErr.Error() != want {
+		t.Errorf("Error() = %q, want %q", rpcErr.Error(), want)
+	}
+}
+
+func TestClientCallTimeout(t *testing.T) {
+	client, server := newFakeServer(t)
+	client.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := client.ToolCall("list_directory", map[string]any{"path": "/slow"})
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("error = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timed out after %v", elapsed)
+	}
+
+	// the late answer is dropped and the connection keeps working
+	late := server.next()
+	server.reply(late, `{"late":true}`)
+
+	call := goCall(client, "after")
+	req := server.next()
+	server.reply(req, `{"ok":true}`)
+	if r := <-call; r.err != nil || string(r.result) != `{"ok":true}` {
+		t.Errorf("call after timeout = %s, %v", r.result, r.err)
+	}
+}
+
+func TestClientClosedServer(t *testing.T) {
+	client, server := newFakeServer(t)
+
+	call := goCall(client, "pending")
+	server.next()
+	server.out.Close() // the server's stdout ends
+
+	if r := <-call; !errors.Is(r.err, ErrClientClosed) {
+		t.Errorf("pending call error = %v, want ErrClientClosed", r.err)
+	}
+	if _, err := client.Call(context.Background(), "later", nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("later call error = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestClientToolCallRPCErrorReturnsAtOnce(t *testing.T) {
+	client, server := newFakeServer(t)
+
+	go func() {
+		req := server.next()
+		server.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32603,"message":"Access denied"}}`, req.ID))
+	}()
+
+	start := time.Now()
+	_, err := client.ToolCall("get_file_info", map[string]any{"path": "/secret"})
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("error = %v, want *RPCError", err)
+	}
+	// lost() would have waited 200ms for a server that is still there
+	if elapsed := time.Since(start); elapsed >= 150*time.Millisecond {
+		t.Errorf("ToolCall took %v for an RPC error", elapsed)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// handshakeTimeout bounds initialize + tools/list, npx based servers can
+// take a while to start
+const handshakeTimeout = 60 * time.Second
+
+// supportedProtocolVersions are the MCP revisions this client speaks,
+// newest first. The first one is what we ask for in initialize.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// requiredTools are the server tools the analyzer can't work without
+var requiredTools = []string{"list_directory", "get_file_info"}
+
+// MCPServerInfo is the serverInfo the server reports in initialize
+type MCPServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// MCPTool is one tool from tools/list
+type MCPTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// initializeResult is the result of the initialize request
+type initializeResult struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    json.RawMessage `json:"capabilities"`
+	ServerInfo      MCPServerInfo   `json:"serverInfo"`
+	Instructions    string          `json:"instructions,omitempty"`
+}
+
+// toolsListResult is one page of tools/list
+type toolsListResult struct {
+	Tools      []MCPTool `json:"tools"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// Initialize runs the MCP lifecycle handshake: initialize, the
+// notifications/initialized notification, then tools/list.
+// Afterwards ProtocolVersion, Server, Capabilities and Tools are filled in.
+func (c *MCPClient) Initialize(ctx context.Context) error {
+	return c.initialize(ctx, c.current())
+}
+
+// initialize runs the handshake on conn, which is also how a restarted
+// server is brought up
+func (c *MCPClient) initialize(ctx context.Context, conn *rpcConn) error {
+	raw, err := conn.callResult(ctx, "initialize", map[string]any{
+		"protocolVersion": supportedProtocolVersions[0],
+		"capabilities":    map[string]any{},
+		"clientInfo": map[string]any{
+			"name":    "filesystem-analyzer",
+			"version": "1.0.0",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("initialize failed: %v", err)
+	}
+
+	var result initializeResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("invalid initialize response: %v", err)
+	}
+	if !supportsProtocolVersion(result.ProtocolVersion) {
+		return fmt.Errorf("server wants MCP protocol version %q, supported: %s",
+			result.ProtocolVersion, strings.Join(supportedProtocolVersions, ", "))
+	}
+
+	c.ProtocolVersion = result.ProtocolVersion
+	if t, ok := conn.transport.(interface{ setProtocolVersion(string) }); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+	c.Server = result.ServerInfo
+	c.Capabilities = result.Capabilities
+
+	if err := conn.notify("notifications/initialized", nil); err != nil {
+		return fmt.Errorf("failed to send initialized notification: %v", err)
+	}
+
+	tools, err := c.listTools(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("tools/list failed: %v", err)
+	}
+	c.Tools = tools
+	return nil
+}
+
+// ListTools asks the server for all its tools, following pagination
+func (c *MCPClient) ListTools(ctx context.Context) (map[string]MCPTool, error) {
+	return c.listTools(ctx, c.current())
+}
+
+func (c *MCPClient) listTools(ctx context.Context, conn *rpcConn) (map[string]MCPTool, error) {
+	tools := map[string]MCPTool{}
+	cursor := ""
+
+	for {
+		var params map[string]any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+
+		raw, err := conn.callResult(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var page toolsListResult
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("invalid tools/list response: %v", err)
+		}
+		for _, tool := range page.Tools {
+			tools[tool.Name] = tool
+		}
+
+		// a server repeating its cursor would loop forever
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// RequireTools fails with the list of missing and available tools when the
+// server lacks any of names
+func (c *MCPClient) RequireTools(names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if _, ok := c.Tools[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	available := make([]string, 0, len(c.Tools))
+	for name := range c.Tools {
+		available = append(available, name)
+	}
+	sort.Strings(available)
+	if len(available) == 0 {
+		available = []string{"none"}
+	}
+
+	return fmt.Errorf("MCP server %s does not provide the %s tool(s) (available: %s)",
+		c.serverName(), strings.Join(missing, ", "), strings.Join(available, ", "))
+}
+
+// serverName is "name version" for messages
+func (c *MCPClient) serverName() string {
+	if c.Server.Name == "" {
+		return "(unnamed)"
+	}
+	return strings.TrimSpace(c.Server.Name + " " + c.Server.Version)
+}
+
+func supportsProtocolVersion(version string) bool {
+	for _, v := range supportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long Close gives the server after closing its stdin, and after the
+// interrupt signal, before moving on to the next step
+const (
+	serverExitGrace      = 3 * time.Second
+	serverInterruptGrace = 2 * time.Second
+)
+
+// serverStderrTail is how many stderr lines are kept for crash messages
+const serverStderrTail = 10
+
+// DefaultServerLogPath is where the server's stderr goes when --server-log
+// isn't given
+func DefaultServerLogPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".go-filesystem-mcp-server.log")
+}
+
+// serverProcess is a server we started, with its stderr going to the log
+type serverProcess struct {
+	cmd    *exec.Cmd
+	stderr *serverLog
+
+	waitOnce sync.Once
+	exited   chan struct{} // closed once the process is reaped
+	exitErr  error
+
+	mu       sync.Mutex
+	stopping bool // exit was asked for, not a crash
+}
+
+// startServer starts a local server and connects to it
+func (c *MCPClient) startServer(cfg *ServerConfig, scanPaths []string) (*rpcConn, error) {
+	cmd := exec.Command(cfg.Command, cfg.commandArgs(scanPaths)...)
+	cmd.Env = cfg.environ()
+	cmd.Dir = cfg.Dir
+	// a server that leaves children holding stderr must not block Wait
+	cmd.WaitDelay = 2 * time.Second
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := openServerLog(serverLogPath)
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		stderr.Close()
+		return nil, errServerStart(cfg, err)
+	}
+	stderr.setPid(cmd.Process.Pid)
+
+	proc := &serverProcess{
+		cmd:    cmd,
+		stderr: stderr,
+		exited: make(chan struct{}),
+	}
+	return c.newConn(newStdioTransport(stdout, stdin), proc), nil
+}
+
+// wait reaps the process once its stdout is done. It returns nil when the
+// exit was asked for and an error carrying the exit status and the last
+// stderr lines when the server died on its own.
+func (p *serverProcess) wait() error {
+	p.waitOnce.Do(func() {
+		err := p.cmd.Wait()
+		p.stderr.Close()
+
+		p.mu.Lock()
+		stopping := p.stopping
+		p.mu.Unlock()
+
+		if !stopping {
+			if err == nil {
+				err = fmt.Errorf("exit status 0")
+			}
+			msg := fmt.Sprintf("MCP server exited unexpectedly (%v)", err)
+			if tail := p.stderr.Tail(); tail != "" {
+				msg += ", last output: " + tail
+			}
+			p.exitErr = fmt.Errorf("%s", msg)
+		}
+		close(p.exited)
+	})
+	<-p.exited
+	return p.exitErr
+}
+
+// stop shuts the server down politely, then less so: its stdin was just
+// closed, if it is still running after serverExitGrace it gets an interrupt
+// (not on Windows), and after serverInterruptGrace it is killed
+func (p *serverProcess) stop() {
+	p.mu.Lock()
+	p.stopping = true
+	p.mu.Unlock()
+
+	select {
+	case <-p.exited:
+		return
+	case <-time.After(serverExitGrace):
+	}
+
+	if p.cmd.Process.Signal(os.Interrupt) == nil {
+		select {
+		case <-p.exited:
+			return
+		case <-time.After(serverInterruptGrace):
+		}
+	}
+
+	p.cmd.Process.Kill()
+	// Don't wait for the reader to see stdout close: a child the server
+	// left behind (npx -> node, a shell wrapper) can hold it open forever.
+	// Wait closes our end itself and WaitDelay bounds the stderr copy.
+	p.wait()
+}
+
+// serverLog receives the server's stderr: every line goes to the log file
+// (when it could be opened) with a timestamp and the pid, and the last few
+// are kept to explain a crash
+type serverLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	pid     int
+	partial []byte
+	tail    []string
+}
+
+// openServerLog appends to path; a log that can't be opened only loses
+// the file, the tail is still kept
+func openServerLog(path string) *serverLog {
+	l := &serverLog{}
+	if path != "" {
+		if f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+			l.file = f
+		}
+	}
+	return l
+}
+
+func (l *serverLog) setPid(pid int) {
+	l.mu.Lock()
+	l.pid = pid
+	l.mu.Unlock()
+}
+
+func (l *serverLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.line(string(bytes.TrimRight(l.partial[:i], "\r")))
+		l.partial = l.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// line records one complete stderr line, l.mu is held
+func (l *serverLog) line(s string) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+	if l.file != nil {
+		fmt.Fprintf(l.file, "%s [pid %d] %s\n", time.Now().Format(time.RFC3339), l.pid, s)
+	}
+	l.tail = append(l.tail, s)
+	if len(l.tail) > serverStderrTail {
+		l.tail = l.tail[len(l.tail)-serverStderrTail:]
+	}
+}
+
+// Tail is the last stderr lines joined for an error message
+func (l *serverLog) Tail() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.tail, " | ")
+}
+
+// Close flushes an unterminated last line and closes the file
+func (l *serverLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.partial) > 0 {
+		l.line(string(l.partial))
+		l.partial = nil
+	}
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
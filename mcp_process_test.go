@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServerEnv makes the test binary run as an MCP server, see
+// TestFakeServerProcess
+const fakeServerEnv = "ANALYZER_FAKE_MCP_SERVER"
+
+// fakeServerConfig starts this test binary as a server in the given mode
+func fakeServerConfig(mode string) *ServerConfig {
+	return &ServerConfig{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestFakeServerProcess$"},
+		Env:     map[string]string{fakeServerEnv: mode},
+	}
+}
+
+// TestFakeServerProcess isn't a test, it is the server other tests start.
+// Modes:
+//
+//	crash:<file>:<n>  exit during the first n tool calls, counted in file
+//	                  across restarts
+//	linger            leave a child holding stdout and ignore interrupts
+//	sleep             just sleep (the lingering child)
+func TestFakeServerProcess(t *testing.T) {
+	mode := os.Getenv(fakeServerEnv)
+	if mode == "" {
+		t.Skip("only runs as a fake server")
+	}
+	if mode == "sleep" {
+		time.Sleep(20 * time.Second)
+		os.Exit(0)
+	}
+
+	var crashFile string
+	var crashes int
+	if rest, ok := strings.CutPrefix(mode, "crash:"); ok {
+		i := strings.LastIndexByte(rest, ':')
+		crashFile = rest[:i]
+		crashes, _ = strconv.Atoi(rest[i+1:])
+	}
+	if mode == "linger" {
+		child := exec.Command(os.Args[0], "-test.run=^TestFakeServerProcess$")
+		child.Env = append(os.Environ(), fakeServerEnv+"=sleep")
+		child.Stdout = os.Stdout
+		if err := child.Start(); err != nil {
+			os.Exit(2)
+		}
+		signal.Ignore(os.Interrupt)
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg rpcMessage
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil || len(msg.ID) == 0 {
+			continue
+		}
+		var result any
+		switch msg.Method {
+		case "initialize":
+			result = map[string]any{
+				"protocolVersion": supportedProtocolVersions[0],
+				"capabilities":    map[string]any{},
+				"serverInfo":      map[string]any{"name": "fake", "version": "1"},
+			}
+		case "tools/list":
+			result = map[string]any{"tools": []any{map[string]any{"name": "get_file_info"}}}
+		case "tools/call":
+			if crashFile != "" {
+				data, _ := os.ReadFile(crashFile)
+				if len(data) < crashes {
+					os.WriteFile(crashFile, append(data, 'x'), 0644)
+					fmt.Fprintf(os.Stderr, "boom %d\n", len(data)+1)
+					os.Exit(3)
+				}
+			}
+			result = map[string]any{"content": []any{map[string]any{"type": "text", "text": "ok"}}}
+		}
+		out.Encode(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+	}
+	if mode != "linger" {
+		os.Exit(0)
+	}
+	select {} // until killed
+}
+
+func TestClientRestartsCrashedServer(t *testing.T) {
+	defer func(path string) { serverLogPath = path }(serverLogPath)
+	serverLogPath = ""
+	counter := filepath.Join(t.TempDir(), "crashes")
+
+	client, err := NewMCPClient(fakeServerConfig("crash:"+counter+":2"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.MaxRestarts = 2
+	var causes []string
+	client.OnRestart = func(cause error, attempt int) {
+		causes = append(causes, fmt.Sprintf("%d: %v", attempt, cause))
+	}
+
+	// the call in flight is retried on the restarted server
+	raw, err := client.ToolCall("get_file_info", map[string]any{"path": "/x"})
+	if err != nil {
+		t.Fatalf("call after two crashes: %v", err)
+	}
+	if !strings.Contains(string(raw), `"ok"`) {
+		t.Errorf("result = %s", raw)
+	}
+	if len(causes) != 2 {
+		t.Fatalf("restarts = %q, want 2", causes)
+	}
+	if !strings.HasPrefix(causes[1], "2: ") || !strings.Contains(causes[1], "exited unexpectedly") || !strings.Contains(causes[1], "boom 2") {
+		t.Errorf("second restart cause = %q, want the exit and the server's last output", causes[1])
+	}
+
+	// the budget is spent, the next crash is final
+	os.WriteFile(counter, nil, 0644)
+	if _, err := client.ToolCall("get_file_info", map[string]any{"path": "/x"}); err == nil || !strings.Contains(err.Error(), "gave up after 2 restarts") {
+		t.Errorf("call with no restarts left = %v, want giving up", err)
+	}
+	if len(causes) != 2 {
+		t.Errorf("restarted again: %q", causes)
+	}
+}
+
+func TestCloseKillsLingeringServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits out the shutdown grace periods")
+	}
+	defer func(path string) { serverLogPath = path }(serverLogPath)
+	serverLogPath = ""
+
+	client, err := NewMCPClient(fakeServerConfig("linger"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(serverExitGrace + serverInterruptGrace + 5*time.Second):
+		t.Fatal("Close hangs while a child holds the server's stdout")
+	}
+}
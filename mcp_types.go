@@ -1,5 +1,11 @@
 package main
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // MCPResponse represents the TOP-LEVEL response sent by an MCP server.
 //
 // This is the direct JSON-RPC envelope.
@@ -20,6 +26,35 @@ type MCPResponse struct {
 
 	//  response
 	Result MCPResult `json:"result"`
+
+	// Error is set instead of Result when the request itself failed
+	// (unknown method, bad params, ...)
+	Error *RPCError `json:"error,omitempty"`
+}
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+)
+
+// RPCError is the JSON-RPC "error" member of a response.
+// MCPClient returns it as the error of a failed call, so callers can
+// errors.As it to look at the code.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	if len(e.Data) > 0 && string(e.Data) != "null" {
+		return fmt.Sprintf("MCP error %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
 }
 
 // MCPResult contains the OUTPUT of a tool execution.
@@ -38,6 +73,32 @@ type MCPResult struct {
 	// - a file reference
 	// - metadata
 	Content []MCPContent `json:"content"`
+
+	// StructuredContent is the typed JSON form of the result, sent by
+	// servers that declare an outputSchema for the tool (MCP 2025-06-18)
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+
+	// IsError is set when the tool itself failed (permission denied,
+	// path outside the allowed directories, ...).
+	// The reason is then in the text blocks of Content.
+	IsError bool `json:"isError,omitempty"`
+}
+
+// Err turns a failed tool result into a Go error (nil when it succeeded)
+func (r MCPResult) Err() error {
+	if !r.IsError {
+		return nil
+	}
+	var msg []string
+	for _, item := range r.Content {
+		if item.Type == "text" && item.Text != "" {
+			msg = append(msg, strings.TrimSpace(item.Text))
+		}
+	}
+	if len(msg) == 0 {
+		return fmt.Errorf("tool call failed")
+	}
+	return fmt.Errorf("%s", strings.Join(msg, "; "))
 }
 
 // MCPContent represents ONE block of content returned by MCP.
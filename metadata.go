@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseWarning is a metadata field the server sent but we couldn't read.
+// The field is left at its zero value; the warning says why.
+type ParseWarning struct {
+	Field string // normalized name: size, created, modified, ...
+	Value string // what the server sent ("" when the field was missing)
+	Err   error
+}
+
+func (w ParseWarning) Error() string {
+	if w.Value == "" {
+		return fmt.Sprintf("%s: %v", w.Field, w.Err)
+	}
+	return fmt.Sprintf("%s: %v (got %q)", w.Field, w.Err, w.Value)
+}
+
+// metadataTimeLayouts are tried in order for timestamps.
+// mark3labs/mcp-filesystem-server uses RFC3339, the Node server prints
+// JavaScript's Date.toString(), some servers print Go's time.String(),
+// ISO 8601 with milliseconds or plain dates.
+var metadataTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.ANSIC,
+	"Mon Jan 02 2006 15:04:05 GMT-0700", // Date.toString(), zone name stripped
+	"2006-01-02",
+}
+
+// metadataFieldAliases maps the normalized spelling of every key we have
+// seen servers use to our field name
+var metadataFieldAliases = map[string]string{
+	"size":               "size",
+	"sizebytes":          "size",
+	"bytes":              "size",
+	"created":            "created",
+	"createdat":          "created",
+	"birthtime":          "created",
+	"modified":           "modified",
+	"modifiedat":         "modified",
+	"mtime":              "modified",
+	"lastmodified":       "modified",
+	"accessed":           "accessed",
+	"accessedat":         "accessed",
+	"atime":              "accessed",
+	"lastaccessed":       "accessed",
+	"isdirectory":        "isDirectory",
+	"isdir":              "isDirectory",
+	"isfile":             "isFile",
+	"mimetype":           "mimeType",
+	"mime":               "mimeType",
+	"contenttype":        "mimeType",
+	"type":               "type", // "file" / "directory" in some servers
+	"path":               "path",
+	"fileinformationfor": "path", // mark3labs: "File information for: <path>"
+	"resourceuri":        "uri",
+	"uri":                "uri",
+}
+
+// ParseFileInfoResult turns a get_file_info result into a FileInfo for
+// path, plus the path the server itself reported for the entry ("" if none).
+//
+// Sources are used in order of reliability: structuredContent (typed JSON),
+// then the "Key: value" lines of the text blocks, then resource blocks (MIME
+// type and URI only). A field set by an earlier source is not overwritten by
+// a later one. Values that are present but unreadable, and the fields every
+// server should report (size, modified), come back as warnings.
+func ParseFileInfoResult(path string, result MCPResult) (*FileInfo, string, []ParseWarning) {
+	p := &metadataParser{
+		info: &FileInfo{Path: path},
+		set:  map[string]bool{},
+	}
+
+	if len(result.StructuredContent) > 0 && string(result.StructuredContent) != "null" {
+		p.parseStructured(result.StructuredContent)
+	}
+
+	for _, item := range result.Content {
+		if item.Type == "text" {
+			p.parseText(item.Text)
+		}
+	}
+
+	for _, item := range result.Content {
+		if item.Type == "resource" && item.Resource != nil {
+			p.setString("mimeType", item.Resource.MimeType)
+			p.setString("uri", item.Resource.URI)
+		}
+	}
+
+	for _, field := range []string{"size", "modified"} {
+		if !p.set[field] && !p.failed(field) {
+			p.warn(field, "", fmt.Errorf("not reported by the server"))
+		}
+	}
+	reported := p.path
+	if reported == "" {
+		reported, _ = fileURIPath(p.uri)
+	}
+	return p.info, reported, p.warnings
+}
+
+// metadataParser collects fields from the different sources
+type metadataParser struct {
+	info     *FileInfo
+	path     string // what the server says the entry's path is
+	uri      string // its resource URI
+	set      map[string]bool
+	warnings []ParseWarning
+}
+
+func (p *metadataParser) warn(field, value string, err error) {
+	p.warnings = append(p.warnings, ParseWarning{Field: field, Value: value, Err: err})
+}
+
+func (p *metadataParser) failed(field string) bool {
+	for _, w := range p.warnings {
+		if w.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStructured reads structuredContent. Keys may sit at the top level or
+// under one wrapper object ({"fileInfo": {...}}).
+func (p *metadataParser) parseStructured(raw json.RawMessage) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		p.warn("structuredContent", string(raw), err)
+		return
+	}
+
+	if len(obj) == 1 {
+		for _, inner := range obj {
+			var nested map[string]json.RawMessage
+			if json.Unmarshal(inner, &nested) == nil {
+				obj = nested
+			}
+		}
+	}
+
+	for key, value := range obj {
+		field, ok := metadataFieldAliases[normalizeMetadataKey(key)]
+		if !ok || p.set[field] {
+			continue
+		}
+
+		// JSON strings are handled like text values, numbers and booleans
+		// get their own handling
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			p.setField(field, s)
+			continue
+		}
+		var n float64
+		if json.Unmarshal(value, &n) == nil {
+			p.setNumber(field, n, string(value))
+			continue
+		}
+		var b bool
+		if json.Unmarshal(value, &b) == nil {
+			p.setField(field, strconv.FormatBool(b))
+			continue
+		}
+		p.warn(field, string(value), fmt.Errorf("unexpected JSON value"))
+	}
+}
+
+// parseText reads "Key: value" lines, e.g. "Size: 68 bytes"
+func (p *metadataParser) parseText(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		field, known := metadataFieldAliases[normalizeMetadataKey(key)]
+		if !known || p.set[field] {
+			continue
+		}
+		p.setField(field, strings.TrimSpace(value))
+	}
+}
+
+// setField parses a textual value into the field
+func (p *metadataParser) setField(field, value string) {
+	switch field {
+	case "size":
+		size, err := parseMetadataSize(value)
+		if err != nil {
+			p.warn(field, value, err)
+			return
+		}
+		p.info.SizeBytes = size
+
+	case "created", "modified", "accessed":
+		t, err := parseMetadataTime(value)
+		if err != nil {
+			p.warn(field, value, err)
+			return
+		}
+		p.setTime(field, t)
+
+	case "isDirectory", "isFile":
+		b, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			p.warn(field, value, fmt.Errorf("not true/false"))
+			return
+		}
+		if field == "isDirectory" {
+			p.info.IsDirectory = b
+		} else {
+			p.info.IsFile = b
+		}
+
+	case "type":
+		switch strings.ToLower(value) {
+		case "file":
+			p.setIfUnset("isFile", func() { p.info.IsFile = true })
+			p.setIfUnset("isDirectory", func() { p.info.IsDirectory = false })
+		case "directory", "dir":
+			p.setIfUnset("isDirectory", func() { p.info.IsDirectory = true })
+			p.setIfUnset("isFile", func() { p.info.IsFile = false })
+		default:
+			return // some other "type", e.g. a MIME description
+		}
+
+	case "mimeType", "path", "uri":
+		p.setString(field, value)
+		return
+	}
+	p.set[field] = true
+}
+
+func (p *metadataParser) setIfUnset(field string, apply func()) {
+	if !p.set[field] {
+		apply()
+		p.set[field] = true
+	}
+}
+
+func (p *metadataParser) setString(field, value string) {
+	if p.set[field] || value == "" {
+		return
+	}
+	switch field {
+	case "mimeType":
+		p.info.MimeType = value
+	case "path":
+		p.path = value
+	case "uri":
+		p.uri = value
+	}
+	p.set[field] = true
+}
+
+// setNumber handles JSON numbers: sizes, and timestamps as Unix seconds
+// or milliseconds
+func (p *metadataParser) setNumber(field string, n float64, raw string) {
+	switch field {
+	case "size":
+		if n < 0 || n != math.Trunc(n) {
+			p.warn(field, raw, fmt.Errorf("not a byte count"))
+			return
+		}
+		p.info.SizeBytes = int64(n)
+	case "created", "modified", "accessed":
+		p.setTime(field, unixTimestamp(n))
+	default:
+		p.warn(field, raw, fmt.Errorf("unexpected number"))
+		return
+	}
+	p.set[field] = true
+}
+
+func (p *metadataParser) setTime(field string, t time.Time) {
+	switch field {
+	case "created":
+		p.info.CreatedAt = t
+	case "modified":
+		p.info.ModifiedAt = t
+	case "accessed":
+		p.info.AccessedAt = t
+	}
+}
+
+// normalizeMetadataKey lowercases a key and drops spaces, dashes and
+// underscores, so "MIME Type", "mime_type" and "mimeType" all match
+func normalizeMetadataKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// metadataSizeUnits are the units servers put after a size, 1024 based
+// like formatFileSize prints them
+var metadataSizeUnits = map[string]float64{
+	"b":     1,
+	"byte":  1,
+	"bytes": 1,
+	"kb":    1 << 10,
+	"kib":   1 << 10,
+	"mb":    1 << 20,
+	"mib":   1 << 20,
+	"gb":    1 << 30,
+	"gib":   1 << 30,
+	"tb":    1 << 40,
+	"tib":   1 << 40,
+}
+
+// parseMetadataSize reads "68", "68 bytes", "1,234 bytes" or "1.5 MB"
+// (the last is only as exact as the server's rounding)
+func parseMetadataSize(value string) (int64, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty size")
+	}
+	number := strings.NewReplacer(",", "", "_", "").Replace(fields[0])
+
+	if len(fields) == 1 || metadataSizeUnits[strings.ToLower(fields[1])] == 1 {
+		size, err := strconv.ParseInt(number, 10, 64)
+		if err != nil || size < 0 {
+			return 0, fmt.Errorf("not a byte count")
+		}
+		return size, nil
+	}
+
+	unit, ok := metadataSizeUnits[strings.ToLower(fields[1])]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", fields[1])
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("not a size")
+	}
+	return int64(math.Round(n * unit)), nil
+}
+
+// parseMetadataTime tries every known layout, then Unix timestamps
+func parseMetadataTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty time")
+	}
+	// "... GMT+0100 (Central European Standard Time)"
+	if i := strings.LastIndex(value, " ("); i > 0 && strings.HasSuffix(value, ")") {
+		value = value[:i]
+	}
+	for _, layout := range metadataTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return unixTimestamp(n), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format")
+}
+
+// unixTimestamp takes seconds, or milliseconds for values too large to be
+// seconds (JavaScript servers send Date.getTime())
+func unixTimestamp(n float64) time.Time {
+	if n > 1e11 {
+		return time.UnixMilli(int64(n))
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// describeFileInfo prints what ParseFileInfoResult found, one field a line
+func describeFileInfo(info *FileInfo, reported string, warnings []ParseWarning) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "path: %s\n", info.Path)
+	fmt.Fprintf(&b, "reported: %s\n", reported)
+	fmt.Fprintf(&b, "size: %d\n", info.SizeBytes)
+	for _, ts := range []struct {
+		name string
+		t    time.Time
+	}{{"created", info.CreatedAt}, {"modified", info.ModifiedAt}, {"accessed", info.AccessedAt}} {
+		fmt.Fprintf(&b, "%s: %s\n", ts.name, ts.t.Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(&b, "isDirectory: %v\n", info.IsDirectory)
+	fmt.Fprintf(&b, "isFile: %v\n", info.IsFile)
+	fmt.Fprintf(&b, "mimeType: %s\n", info.MimeType)
+	for _, w := range warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w.Error())
+	}
+	return b.String()
+}
+
+func TestParseFileInfoResultGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "get_file_info", "*.json"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no testdata: %v", err)
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resp MCPResponse
+			if err := json.Unmarshal(data, &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			info, reported, warnings := ParseFileInfoResult("/listed/"+name, resp.Result)
+			got := describeFileInfo(info, reported, warnings)
+
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseFileInfoResult(t *testing.T) {
+	jan9 := time.Date(2026, 1, 9, 10, 11, 12, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		result   MCPResult
+		size     int64
+		modified time.Time
+		isDir    bool
+		mimeType string
+		reported string
+		warnings []string // fields
+	}{
+		{
+			name: "structured content",
+			result: MCPResult{StructuredContent: json.RawMessage(
+				`{"size": 68, "modified": "2026-01-09T10:11:12Z", "isDirectory": false, "mimeType": "text/plain", "path": "/srv/a.txt"}`)},
+			size: 68, modified: jan9, mimeType: "text/plain", reported: "/srv/a.txt",
+		},
+		{
+			name: "structured content in a wrapper, millisecond timestamps",
+			result: MCPResult{StructuredContent: json.RawMessage(
+				`{"fileInfo": {"sizeBytes": 4096, "mtime": 1767953472000, "type": "directory"}}`)},
+			size: 4096, modified: jan9, isDir: true,
+		},
+		{
+			name: "structured content wins over text",
+			result: MCPResult{
+				StructuredContent: json.RawMessage(`{"size": 10, "modified": 1767953472}`),
+				Content:           []MCPContent{{Type: "text", Text: "Size: 99 bytes\nModified: 2020-01-01T00:00:00Z"}},
+			},
+			size: 10, modified: jan9,
+		},
+		{
+			name: "text fallback",
+			result: MCPResult{Content: []MCPContent{
+				{Type: "text", Text: "Some header\n\nSize: 1,234 bytes\nLast Modified: 2026-01-09 10:11:12\nIs Directory: false\nMIME Type: application/pdf"},
+			}},
+			size: 1234, modified: jan9, mimeType: "application/pdf",
+		},
+		{
+			name: "text sizes with units",
+			result: MCPResult{Content: []MCPContent{
+				{Type: "text", Text: "size: 1.5 MB\nmodified: 2026-01-09"},
+			}},
+			size: 1572864, modified: time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "resource block gives MIME type and path",
+			result: MCPResult{Content: []MCPContent{
+				{Type: "text", Text: "size: 5\nmodified: 2026-01-09T10:11:12Z"},
+				{Type: "resource", Resource: &MCPResource{URI: "file:///srv/b.txt", MimeType: "text/plain"}},
+			}},
+			size: 5, modified: jan9, mimeType: "text/plain", reported: "/srv/b.txt",
+		},
+		{
+			name: "bad size and time values",
+			result: MCPResult{Content: []MCPContent{
+				{Type: "text", Text: "size: lots\nmodified: yesterday\ncreated: \nisDirectory: maybe"},
+			}},
+			warnings: []string{"size", "modified", "created", "isDirectory"},
+		},
+		{
+			name: "bad structured values",
+			result: MCPResult{StructuredContent: json.RawMessage(
+				`{"size": -1, "modified": {"when": "now"}, "accessed": 12.5e400}`)},
+			warnings: []string{"accessed", "modified", "size"},
+		},
+		{
+			name: "fractional and unknown size units",
+			result: MCPResult{StructuredContent: json.RawMessage(
+				`{"size": 1.5, "created": "2026-01-09", "modified": "2026-01-09"}`),
+				Content: []MCPContent{{Type: "text", Text: "size: 12 parsecs"}}},
+			modified: time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC),
+			warnings: []string{"size", "size"},
+		},
+		{
+			name:     "missing fields",
+			result:   MCPResult{Content: []MCPContent{{Type: "text", Text: "isFile: true"}}},
+			warnings: []string{"size", "modified"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, reported, warnings := ParseFileInfoResult("/listed", tt.result)
+			if info.SizeBytes != tt.size {
+				t.Errorf("size = %d, want %d", info.SizeBytes, tt.size)
+			}
+			if !info.ModifiedAt.Equal(tt.modified) {
+				t.Errorf("modified = %v, want %v", info.ModifiedAt, tt.modified)
+			}
+			if info.IsDirectory != tt.isDir {
+				t.Errorf("isDirectory = %v, want %v", info.IsDirectory, tt.isDir)
+			}
+			if info.MimeType != tt.mimeType {
+				t.Errorf("mimeType = %q, want %q", info.MimeType, tt.mimeType)
+			}
+			if reported != tt.reported {
+				t.Errorf("reported = %q, want %q", reported, tt.reported)
+			}
+
+			// structuredContent is a map, its warnings come in any order
+			var fields []string
+			for _, w := range warnings {
+				fields = append(fields, w.Field)
+			}
+			sort.Strings(fields)
+			want := append([]string(nil), tt.warnings...)
+			sort.Strings(want)
+			if strings.Join(fields, ",") != strings.Join(want, ",") {
+				t.Errorf("warnings = %v, want fields %v", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestParseMetadataTimeLayouts(t *testing.T) {
+	ref := time.Date(2026, 1, 9, 10, 11, 12, 345678000, time.UTC)
+
+	for _, layout := range metadataTimeLayouts {
+		value := ref.Format(layout)
+		want := ref.Truncate(time.Second)
+		switch {
+		case strings.Contains(layout, ".999999999"):
+			want = ref
+		case !strings.Contains(layout, "15"):
+			want = time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)
+		}
+
+		got, err := parseMetadataTime(value)
+		if err != nil {
+			t.Errorf("layout %q: %q: %v", layout, value, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("layout %q: %q parsed as %v, want %v", layout, value, got, want)
+		}
+	}
+
+	// the JavaScript form with its zone name, and Unix timestamps
+	for value, want := range map[string]time.Time{
+		"Fri Jan 09 2026 11:11:12 GMT+0100 (Central European Standard Time)": ref.Truncate(time.Second),
+		"1767953472":    ref.Truncate(time.Second),
+		"1767953472345": ref.Truncate(time.Millisecond),
+	} {
+		got, err := parseMetadataTime(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%q parsed as %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday", "2026-13-45", "09/01/2026"} {
+		if _, err := parseMetadataTime(bad); err == nil {
+			t.Errorf("%q parsed without error", bad)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mountEntry is one line of /proc/self/mountinfo
+type mountEntry struct {
+	MountPoint string
+	FSType     string // e.g. "ext4", "fuse.rclone"
+	Source     string // e.g. "/dev/sda1", "gdrive:"
+	Options    []string
+}
+
+// readMounts parses /proc/self/mountinfo.
+//
+// Format (see proc(5)):
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//	(1)(2)(3)   (4)   (5)      (6)      (7)   (8) (9)   (10)         (11)
+func readMounts() ([]mountEntry, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// optional fields end with a lone "-"
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep == -1 || len(fields) < sep+3 {
+			continue
+		}
+
+		mounts = append(mounts, mountEntry{
+			MountPoint: unescapeMountField(fields[4]),
+			Options:    strings.Split(fields[5], ","),
+			FSType:     fields[sep+1],
+			Source:     unescapeMountField(fields[sep+2]),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField decodes the octal escapes (\040 = space) the kernel
+// uses for whitespace and backslashes in mountinfo
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// findMount returns the mount that contains path (longest matching mount point)
+func findMount(path string) (mountEntry, bool) {
+	mounts, err := readMounts()
+	if err != nil {
+		return mountEntry{}, false
+	}
+
+	path = canonicalPath(path)
+
+	var best mountEntry
+	found := false
+	for _, m := range mounts {
+		if !pathHasPrefix(path, m.MountPoint) {
+			continue
+		}
+		if !found || len(m.MountPoint) >= len(best.MountPoint) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+// pathHasPrefix reports whether path is dir or inside dir
+func pathHasPrefix(path, dir string) bool {
+	if dir == "/" {
+		return true
+	}
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
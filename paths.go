@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Paths come from the MCP server, which isn't necessarily on this OS: a
+// Windows server sends C:\... and \\server\share\..., a server in WSL sends
+// /mnt/c/..., and some send file:// URIs. The helpers here take any of
+// those forms; filepath only knows the local one.
+
+// getFileName returns the last element of path with its extension.
+// Backslashes separate only on Windows and in Windows-style paths,
+// elsewhere they can be part of a name.
+func getFileName(path string) string {
+	path = pathFromURI(path)
+	seps := "/"
+	if usesBackslashes(path) {
+		seps = `/\`
+	}
+	path = strings.TrimRight(path, seps)
+	if i := strings.LastIndexAny(path, seps); i >= 0 {
+		path = path[i+1:]
+	}
+	return path
+}
+
+// parentPath is the directory containing path, in the same form and by
+// the same separator rules as getFileName; "" when path has no parent
+func parentPath(path string) string {
+	seps := "/"
+	if usesBackslashes(path) {
+		seps = `/\`
+	}
+	trimmed := strings.TrimRight(path, seps)
+	i := strings.LastIndexAny(trimmed, seps)
+	switch {
+	case i < 0:
+		return ""
+	case i == 0:
+		return trimmed[:1] // "/"
+	case i == 2 && hasDriveLetter(trimmed):
+		return trimmed[:3] // "C:\"
+	}
+	return trimmed[:i]
+}
+
+// joinPath appends name to the directory dir in dir's own form, so a
+// Windows server's C:\docs gets a backslash here too
+func joinPath(dir, name string) string {
+	if runtime.GOOS != "windows" && usesBackslashes(dir) {
+		return strings.TrimRight(dir, `/\`) + `\` + name
+	}
+	return filepath.Join(dir, name)
+}
+
+// getExtension returns the extension of a file name including the dot,
+// "" when there is none
+func getExtension(filename string) string {
+	dotIndex := strings.LastIndex(filename, ".")
+	if dotIndex == -1 {
+		return ""
+	}
+	return filename[dotIndex:]
+}
+
+// pathFromURI turns a file:// URI into a path, anything else is returned
+// as it is
+func pathFromURI(path string) string {
+	if p, ok := fileURIPath(path); ok {
+		return p
+	}
+	return path
+}
+
+// usesBackslashes reports whether backslashes separate path elements
+func usesBackslashes(path string) bool {
+	return runtime.GOOS == "windows" || hasDriveLetter(path) || strings.HasPrefix(path, `\\`)
+}
+
+// hasDriveLetter reports whether path starts with "C:", "C:\" or "C:/"
+func hasDriveLetter(path string) bool {
+	if len(path) < 2 || path[1] != ':' || !isASCIILetter(path[0]) {
+		return false
+	}
+	return len(path) == 2 || path[2] == '\\' || path[2] == '/'
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// wslDrivePath splits "/mnt/c/Users/x" into "c" and "/Users/x"
+func wslDrivePath(path string) (drive, rest string, ok bool) {
+	after, found := strings.CutPrefix(path, "/mnt/")
+	if !found || len(after) == 0 || !isASCIILetter(after[0]) {
+		return "", "", false
+	}
+	if len(after) > 1 && after[1] != '/' {
+		return "", "", false // /mnt/data is a mount, not a drive
+	}
+	return after[:1], after[1:], true
+}
+
+// localPath converts a path as the server reported it into one this OS
+// can open, for the checks that read files directly and for --delete.
+// URIs become paths; WSL's /mnt/c/... becomes C:\... on Windows, and C:\...
+// becomes /mnt/c/... inside WSL (where /mnt/c exists).
+func localPath(path string) string {
+	path = pathFromURI(path)
+
+	if runtime.GOOS == "windows" {
+		if drive, rest, ok := wslDrivePath(path); ok {
+			if rest == "" {
+				rest = "/"
+			}
+			return strings.ToUpper(drive) + ":" + filepath.FromSlash(rest)
+		}
+		return filepath.FromSlash(path)
+	}
+
+	if hasDriveLetter(path) {
+		mount := "/mnt/" + strings.ToLower(path[:1])
+		if fi, err := os.Stat(mount); err == nil && fi.IsDir() {
+			rest := strings.ReplaceAll(path[2:], `\`, "/")
+			return strings.TrimSuffix(mount+"/"+strings.TrimPrefix(rest, "/"), "/")
+		}
+	}
+	return path
+}
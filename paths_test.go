@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileURIPath(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+		ok   bool
+	}{
+		{"file:///home/u/a.txt", "/home/u/a.txt", true},
+		{"FILE:///home/u/a.txt", "/home/u/a.txt", true},
+		{"file://localhost/home/u/a.txt", "/home/u/a.txt", true},
+		{"file:///docs/My%20Report.pdf", "/docs/My Report.pdf", true},
+		{"file:///docs/My Report.pdf", "/docs/My Report.pdf", true}, // raw path glued on
+		{"file:///docs/100%.txt", "/docs/100%.txt", true},           // not an escape
+		{"file:///docs/dir/", "/docs/dir", true},
+		{"file:///", "/", true},
+		{"file:///C:/Users/x/a.txt", "C:/Users/x/a.txt", true},
+		{"file:///c%3A/Users/x", "c:/Users/x", true},
+		{`file://C:\Users\x\a.txt`, `C:\Users\x\a.txt`, true},
+		{"file:///C:/", "C:/", true},
+		{"file://server/share/a.txt", `\\server\share\a.txt`, true},
+		{"file:////server/share/a.txt", `\\server\share\a.txt`, true},
+		{`file://\\server\share\a.txt`, `\\server\share\a.txt`, true},
+		{"file:///mnt/c/Users/x", "/mnt/c/Users/x", true},
+		{"/home/u/a.txt", "", false},
+		{"https://example.com/a.txt", "", false},
+		{"file://", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := fileURIPath(tt.uri)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("fileURIPath(%q) = %q, %v; want %q, %v", tt.uri, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetFileName(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"/home/u/a.txt", "a.txt"},
+		{"/home/u/dir/", "dir"},
+		{"a.txt", "a.txt"},
+		{`C:\docs\b.pdf`, "b.pdf"},
+		{`C:/docs/b.pdf`, "b.pdf"},
+		{`C:\docs\sub\`, "sub"},
+		{`\\server\share\c.doc`, "c.doc"},
+		{"file:///docs/My%20Report.pdf", "My Report.pdf"},
+		{"file:///C:/docs/b.pdf", "b.pdf"},
+		{"/mnt/c/Users/x/d.txt", "d.txt"},
+	}
+	if runtime.GOOS != "windows" {
+		// a backslash is part of a Unix name
+		tests = append(tests, struct{ path, want string }{`/tmp/a\b.txt`, `a\b.txt`})
+	}
+	for _, tt := range tests {
+		if got := getFileName(tt.path); got != tt.want {
+			t.Errorf("getFileName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParentPath(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"/home/u/a.txt", "/home/u"},
+		{"/home/u/dir/", "/home/u"},
+		{"/a.txt", "/"},
+		{"a.txt", ""},
+		{`C:\docs\b.pdf`, `C:\docs`},
+		{`C:\b.pdf`, `C:\`},
+		{`C:/docs/b.pdf`, `C:/docs`},
+		{`\\server\share\c.doc`, `\\server\share`},
+		{"/mnt/c/Users/x", "/mnt/c/Users"},
+	}
+	for _, tt := range tests {
+		if got := parentPath(tt.path); got != tt.want {
+			t.Errorf("parentPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	if got, want := joinPath(`C:\docs`, "b.pdf"), `C:\docs\b.pdf`; got != want {
+		t.Errorf("joinPath = %q, want %q", got, want)
+	}
+	if got, want := joinPath(`C:\`, "b.pdf"), `C:\b.pdf`; got != want {
+		t.Errorf("joinPath = %q, want %q", got, want)
+	}
+	if got, want := joinPath("/docs", "b.pdf"), filepath.Join("/docs", "b.pdf"); got != want {
+		t.Errorf("joinPath = %q, want %q", got, want)
+	}
+}
+
+func TestWSLDrivePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		drive, rest string
+		ok          bool
+	}{
+		{"/mnt/c/Users/x", "c", "/Users/x", true},
+		{"/mnt/D/data", "D", "/data", true},
+		{"/mnt/c", "c", "", true},
+		{"/mnt/c/", "c", "/", true},
+		{"/mnt/data/x", "", "", false}, // a mount, not a drive
+		{"/mnt/", "", "", false},
+		{"/mnt/1/x", "", "", false},
+		{"/home/mnt/c/x", "", "", false},
+		{`C:\Users`, "", "", false},
+	}
+	for _, tt := range tests {
+		drive, rest, ok := wslDrivePath(tt.path)
+		if drive != tt.drive || rest != tt.rest || ok != tt.ok {
+			t.Errorf("wslDrivePath(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.path, drive, rest, ok, tt.drive, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		tests := []struct{ path, want string }{
+			{"/mnt/c/Users/x/a.txt", `C:\Users\x\a.txt`},
+			{"/mnt/d", `D:\`},
+			{"file:///C:/Users/x/a.txt", `C:\Users\x\a.txt`},
+			{"file://server/share/a.txt", `\\server\share\a.txt`},
+			{`C:\Users\x`, `C:\Users\x`},
+			{"C:/Users/x", `C:\Users\x`},
+		}
+		for _, tt := range tests {
+			if got := localPath(tt.path); got != tt.want {
+				t.Errorf("localPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		}
+		return
+	}
+
+	// drive letters only map to /mnt/<drive> inside WSL, where it exists
+	driveC := `C:\Users\x\a.txt`
+	if fi, err := os.Stat("/mnt/c"); err == nil && fi.IsDir() {
+		driveC = "/mnt/c/Users/x/a.txt"
+	}
+	tests := []struct{ path, want string }{
+		{"/home/u/a.txt", "/home/u/a.txt"},
+		{"file:///home/u/My%20Report.pdf", "/home/u/My Report.pdf"},
+		{"/mnt/c/Users/x", "/mnt/c/Users/x"},
+		{`C:\Users\x\a.txt`, driveC},
+		{`\\server\share\a.txt`, `\\server\share\a.txt`},
+	}
+	for _, tt := range tests {
+		if got := localPath(tt.path); got != tt.want {
+			t.Errorf("localPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseListingWindowsServer(t *testing.T) {
+	// what a Windows server sends, read on any OS
+	result := MCPResult{Content: []MCPContent{
+		{Type: "resource", Resource: &MCPResource{URI: "file:///C:/docs", MimeType: "inode/directory"}},
+		{Type: "resource", Resource: &MCPResource{URI: "file:///C:/docs/b.pdf", MimeType: "application/pdf"}},
+		{Type: "resource", Resource: &MCPResource{URI: "file:///C:/docs/sub/", Text: "[DIR] sub"}},
+	}}
+	entries := parseListing("C:/docs", result)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.Name != "b.pdf" || e.Path != "C:/docs/b.pdf" || e.Kind != EntryFile {
+		t.Errorf("file entry = %+v", e)
+	}
+	if e := entries[1]; e.Name != "sub" || e.Kind != EntryDir {
+		t.Errorf("directory entry = %+v", e)
+	}
+
+	text := MCPResult{Content: []MCPContent{{Type: "text", Text: "[FILE] b.pdf\n[DIR] sub\r\n"}}}
+	entries = parseListing(`C:\docs`, text)
+	if len(entries) != 2 || entries[0].Path != `C:\docs\b.pdf` || entries[1].Path != `C:\docs\sub` {
+		t.Errorf("text entries = %+v", entries)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// ignoreFileName is read in every scanned directory, its patterns apply to
+// that directory and everything below it
+const ignoreFileName = ".analyzerignore"
+
+// pathPattern is one --include/--exclude value or .analyzerignore line
+type pathPattern struct {
+	source  string
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes what an earlier pattern matched
+	dirOnly bool // "build/" matches directories only
+}
+
+// PatternList is a list of gitignore-style patterns, matched against paths
+// relative to one directory and separated by "/".
+//
+//	*.tmp         any file named *.tmp, at any depth
+//	/notes.txt    only notes.txt directly in the directory
+//	docs/*.pdf    PDFs directly in docs
+//	**/cache/     every directory named cache
+//	build/**      everything below build
+//	!keep.tmp     take keep.tmp back out of the matches
+//	re:\.bak\d*$  a regular expression on the whole relative path
+type PatternList struct {
+	patterns []pathPattern
+}
+
+// ParsePatterns compiles patterns; blank lines and "#" comments are skipped
+// so the lines of an ignore file can be passed as they are
+func ParsePatterns(lines []string) (*PatternList, error) {
+	l := &PatternList{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := compilePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		l.patterns = append(l.patterns, p)
+	}
+	return l, nil
+}
+
+func compilePattern(s string) (pathPattern, error) {
+	p := pathPattern{source: s}
+
+	if expr, ok := strings.CutPrefix(s, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, fmt.Errorf("invalid pattern %q: %v", s, err)
+		}
+		p.re = re
+		return p, nil
+	}
+
+	glob := strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(glob, "!"); ok {
+		p.negate = true
+		glob = rest
+	}
+	glob = strings.TrimPrefix(glob, `\`) // "\!name" and "\#name" are literal
+	if strings.HasSuffix(glob, "/") {
+		p.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+	if glob == "" {
+		return p, fmt.Errorf("invalid pattern %q: nothing to match", s)
+	}
+
+	// like gitignore, a pattern with a slash is anchored to the directory,
+	// one without matches a name at any depth
+	anchor := `(?:^|/)`
+	if strings.Contains(glob, "/") {
+		anchor = `^`
+		glob = strings.TrimPrefix(glob, "/")
+	}
+
+	body, err := globToRegexp(glob)
+	if err != nil {
+		return p, fmt.Errorf("invalid pattern %q: %v", s, err)
+	}
+	expr := anchor + body + `$`
+	if runtime.GOOS == "windows" {
+		expr = `(?i)` + expr
+	}
+	p.re, err = regexp.Compile(expr)
+	if err != nil {
+		return p, fmt.Errorf("invalid pattern %q: %v", s, err)
+	}
+	return p, nil
+}
+
+// globToRegexp translates * ? [...] and ** into a regular expression.
+// * and ? stay within one path element, ** crosses directories.
+func globToRegexp(glob string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if !strings.HasPrefix(glob[i:], "**") {
+				b.WriteString(`[^/]*`)
+				continue
+			}
+			atStart := i == 0 || glob[i-1] == '/'
+			i++ // the second *
+			switch {
+			case atStart && i+1 < len(glob) && glob[i+1] == '/':
+				b.WriteString(`(?:.*/)?`) // "**/" also matches no directory
+				i++
+			default:
+				b.WriteString(`.*`)
+			}
+		case '?':
+			b.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated [")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+// Len is the number of patterns
+func (l *PatternList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.patterns)
+}
+
+// String lists the patterns as they were written
+func (l *PatternList) String() string {
+	if l.Len() == 0 {
+		return "(none)"
+	}
+	sources := make([]string, len(l.patterns))
+	for i, p := range l.patterns {
+		sources[i] = p.source
+	}
+	return strings.Join(sources, ", ")
+}
+
+// Match reports whether rel is matched. A matched parent directory matches
+// everything below it, as in gitignore.
+func (l *PatternList) Match(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if matched, _ := l.decide(strings.Join(parts[:i], "/"), true); matched {
+			return true
+		}
+	}
+	matched, _ := l.decide(rel, isDir)
+	return matched
+}
+
+// decide looks at rel alone, not its parents. The last pattern that matches
+// wins; decided is false when none does, so lists further down the tree
+// can be asked next.
+func (l *PatternList) decide(rel string, isDir bool) (matched, decided bool) {
+	if l == nil {
+		return false, false
+	}
+	for _, p := range l.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			matched, decided = !p.negate, true
+		}
+	}
+	return matched, decided
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPatternListMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		rel      string
+		isDir    bool
+		want     bool
+	}{
+		// no slash: a name at any depth
+		{[]string{"*.tmp"}, "a.tmp", false, true},
+		{[]string{"*.tmp"}, "x/y/a.tmp", false, true},
+		{[]string{"*.tmp"}, "a.tmp.txt", false, false},
+		{[]string{"*.tmp"}, "x.tmp/a.txt", false, true}, // through the matched directory
+		{[]string{"a?c"}, "abc", false, true},
+		{[]string{"a?c"}, "a/c", false, false},
+
+		// a leading or inner slash anchors to the directory
+		{[]string{"/notes.txt"}, "notes.txt", false, true},
+		{[]string{"/notes.txt"}, "docs/notes.txt", false, false},
+		{[]string{"docs/*.pdf"}, "docs/a.pdf", false, true},
+		{[]string{"docs/*.pdf"}, "x/docs/a.pdf", false, false},
+		{[]string{"docs/*.pdf"}, "docs/sub/a.pdf", false, false},
+
+		// **
+		{[]string{"**/cache"}, "cache", true, true},
+		{[]string{"**/cache"}, "a/b/cache", true, true},
+		{[]string{"build/**"}, "build", true, false},
+		{[]string{"build/**"}, "build/out.o", false, true},
+		{[]string{"build/**"}, "build/x/y/out.o", false, true},
+		{[]string{"a/**/b"}, "a/b", false, true},
+		{[]string{"a/**/b"}, "a/x/y/b", false, true},
+		{[]string{"a/**/b"}, "a/xb", false, false},
+		{[]string{"a/**/b"}, "c/a/x/b", false, false},
+
+		// trailing slash: directories only, and what is below them
+		{[]string{"build/"}, "build", true, true},
+		{[]string{"build/"}, "build", false, false},
+		{[]string{"build/"}, "src/build", true, true},
+		{[]string{"build/"}, "build/out.o", false, true},
+		{[]string{"**/cache/"}, "a/cache", false, false},
+
+		// negation: the last matching pattern wins
+		{[]string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{[]string{"*.log", "!keep.log"}, "other.log", false, true},
+		{[]string{"!keep.log", "*.log"}, "keep.log", false, true},
+		// a file can't be taken back out of an excluded directory
+		{[]string{"build/", "!build/keep"}, "build/keep", false, true},
+
+		// classes
+		{[]string{"file[0-9].txt"}, "file3.txt", false, true},
+		{[]string{"file[0-9].txt"}, "filex.txt", false, false},
+		{[]string{"file[!0-9].txt"}, "filex.txt", false, true},
+		{[]string{"file[!0-9].txt"}, "file3.txt", false, false},
+		{[]string{"[!.]*"}, ".hidden", false, false},
+
+		// escapes
+		{[]string{`\!important`}, "!important", false, true},
+		{[]string{`\#notes`}, "#notes", false, true},
+		{[]string{`a\*b`}, "a*b", false, true},
+		{[]string{`a\*b`}, "axxb", false, false},
+		{[]string{`a.b`}, "axb", false, false}, // regexp characters are literal
+
+		// regular expressions on the whole relative path
+		{[]string{`re:\.bak\d*$`}, "x/y.bak12", false, true},
+		{[]string{`re:\.bak\d*$`}, "y.bak.txt", false, false},
+		{[]string{`re:^docs/`}, "docs/a.pdf", false, true},
+		{[]string{`re:^docs/`}, "x/docs/a.pdf", false, false},
+
+		// comments and blank lines
+		{[]string{"# *.txt", "", "  "}, "a.txt", false, false},
+	}
+	for _, tt := range tests {
+		list, err := ParsePatterns(tt.patterns)
+		if err != nil {
+			t.Errorf("ParsePatterns(%q): %v", tt.patterns, err)
+			continue
+		}
+		if got := list.Match(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("%q.Match(%q, dir=%v) = %v, want %v", tt.patterns, tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestPatternCase(t *testing.T) {
+	list, err := ParsePatterns([]string{"*.TMP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// case only matters where the filesystem cares
+	if got, want := list.Match("a.tmp", false), runtime.GOOS == "windows"; got != want {
+		t.Errorf("*.TMP matches a.tmp = %v, want %v", got, want)
+	}
+}
+
+func TestParsePatternsErrors(t *testing.T) {
+	for _, bad := range []string{"[abc", "re:(", "!", "/"} {
+		if _, err := ParsePatterns([]string{bad}); err == nil {
+			t.Errorf("ParsePatterns(%q) accepted an invalid pattern", bad)
+		}
+	}
+}
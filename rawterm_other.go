@@ -0,0 +1,20 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func makeRaw(in, out *os.File) (func(), error) {
+	return nil, fmt.Errorf("raw terminal mode is not supported on this platform")
+}
+
+func terminalSize(out *os.File) (int, int, error) {
+	return 0, 0, fmt.Errorf("terminal size is not available on this platform")
+}
+
+func notifyResize(out *os.File, ch chan<- struct{}) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Console modes, see SetConsoleMode
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// consoleScreenBufferInfo is CONSOLE_SCREEN_BUFFER_INFO
+type consoleScreenBufferInfo struct {
+	sizeX, sizeY     int16
+	cursorX, cursorY int16
+	attributes       uint16
+	left, top        int16
+	right, bottom    int16
+	maxX, maxY       int16
+}
+
+func setConsoleMode(f *os.File, mode uint32) error {
+	ret, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(mode))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
+
+// makeRaw turns off line input and echo on the console and switches both
+// directions to VT sequences, which the browser reads and writes. The
+// returned func restores the previous modes.
+func makeRaw(in, out *os.File) (func(), error) {
+	var inMode, outMode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(in.Fd()), &inMode); err != nil {
+		return nil, err
+	}
+	if err := syscall.GetConsoleMode(syscall.Handle(out.Fd()), &outMode); err != nil {
+		return nil, err
+	}
+
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(in, raw); err != nil {
+		return nil, err
+	}
+	if err := setConsoleMode(out, outMode|enableVirtualTerminalProcessing); err != nil {
+		setConsoleMode(in, inMode)
+		return nil, err
+	}
+	return func() {
+		setConsoleMode(in, inMode)
+		setConsoleMode(out, outMode)
+	}, nil
+}
+
+// terminalSize returns the columns and rows of the console window
+func terminalSize(out *os.File) (int, int, error) {
+	var info consoleScreenBufferInfo
+	ret, _, err := procGetConsoleScreenBufferInfo.Call(out.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return 0, 0, err
+	}
+	return int(info.right-info.left) + 1, int(info.bottom-info.top) + 1, nil
+}
+
+// notifyResize sends on ch whenever the console window changes size. The
+// console has no resize signal, so its size is polled.
+func notifyResize(out *os.File, ch chan<- struct{}) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		w, h, _ := terminalSize(out)
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				nw, nh, err := terminalSize(out)
+				if err != nil || (nw == w && nh == h) {
+					continue
+				}
+				w, h = nw, nh
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
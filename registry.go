@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity says how much attention a finding deserves
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Rule is one check run against every scanned file.
+// Adding a check means implementing this and calling RegisterRule,
+// main, --delete and the reports pick it up from the registry.
+type Rule interface {
+	// ID names the rule in --rules, reports and the summary ("unused")
+	ID() string
+
+	Severity() Severity
+
+	// Evaluate returns why the file is flagged, or nil if it isn't
+	Evaluate(info *FileInfo) *Explanation
+}
+
+// Preparer is implemented by rules that need to see every file before
+// evaluating any of them (e.g. duplicates compare files with each other)
+type Preparer interface {
+	Prepare(files []*FileInfo)
+}
+
+// Summarizer is implemented by rules that have more to show after all
+// files were evaluated (e.g. the list of duplicate sets)
+type Summarizer interface {
+	Summarize(report *Report)
+}
+
+// Informational is implemented by rules whose findings are worth a look
+// but not space to free (a mismatched extension), they stay out of the
+// reclaimable totals
+type Informational interface {
+	Informational()
+}
+
+// ruleEntry is one registered rule
+type ruleEntry struct {
+	id          string
+	description string
+	byDefault   bool // enabled when --rules isn't given
+	factory     func() Rule
+}
+
+// ruleRegistry holds every known rule in evaluation (and print) order
+var ruleRegistry []ruleEntry
+
+// RegisterRule adds a rule to the registry. The factory runs after flags
+// are parsed, so it can read the rule's settings (--unused-days, ...).
+func RegisterRule(id, description string, byDefault bool, factory func() Rule) {
+	for _, e := range ruleRegistry {
+		if e.id == id {
+			panic("rule registered twice: " + id)
+		}
+	}
+	ruleRegistry = append(ruleRegistry, ruleEntry{
+		id:          id,
+		description: description,
+		byDefault:   byDefault,
+		factory:     factory,
+	})
+}
+
+// ruleIDs lists every registered rule ID, for help and error messages
+func ruleIDs() []string {
+	ids := make([]string, 0, len(ruleRegistry))
+	for _, e := range ruleRegistry {
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+// BuildRules turns a --rules value into rule instances.
+// "" selects the default rules, "all" every rule, otherwise a comma
+// separated list of IDs. Rules come back in registry order.
+func BuildRules(spec string) ([]Rule, error) {
+	spec = strings.TrimSpace(spec)
+
+	wanted := map[string]bool{}
+	switch spec {
+	case "":
+		for _, e := range ruleRegistry {
+			wanted[e.id] = e.byDefault
+		}
+	case "all":
+		for _, e := range ruleRegistry {
+			wanted[e.id] = true
+		}
+	default:
+		known := map[string]bool{}
+		for _, e := range ruleRegistry {
+			known[e.id] = true
+		}
+		for _, id := range strings.Split(spec, ",") {
+			id = strings.ToLower(strings.TrimSpace(id))
+			if id == "" {
+				continue
+			}
+			if !known[id] {
+				return nil, fmt.Errorf("unknown rule %q (available: %s)", id, strings.Join(ruleIDs(), ", "))
+			}
+			wanted[id] = true
+		}
+	}
+
+	var rules []Rule
+	for _, e := range ruleRegistry {
+		if wanted[e.id] {
+			rules = append(rules, e.factory())
+		}
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules selected")
+	}
+	return rules, nil
+}
+
+// hasRule reports whether a rule with that ID is active
+func hasRule(rules []Rule, id string) bool {
+	for _, r := range rules {
+		if r.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFinding reports whether the rule with that ID fired
+func hasFinding(findings []RuleFinding, id string) bool {
+	for _, f := range findings {
+		if f.Rule.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRuleIDs lists the rules that run when --rules isn't given
+func defaultRuleIDs() []string {
+	var ids []string
+	for _, e := range ruleRegistry {
+		if e.byDefault {
+			ids = append(ids, e.id)
+		}
+	}
+	return ids
+}
+
+// activeRuleIDs lists the IDs of the given rules
+func activeRuleIDs(rules []Rule) []string {
+	ids := make([]string, 0, len(rules))
+	for _, r := range rules {
+		ids = append(ids, r.ID())
+	}
+	return ids
+}
+
+// findingSummary is the one-line version of an explanation used in
+// --delete prompts: the reason plus the first piece of evidence
+func findingSummary(exp *Explanation) string {
+	if len(exp.Evidence) == 0 {
+		return exp.Reason
+	}
+	return exp.Reason + " (" + exp.Evidence[0] + ")"
+}
+
+// RuleFinding pairs a rule with what it found for one file
+type RuleFinding struct {
+	Rule        Rule
+	Explanation *Explanation
+}
+
+// PrepareRules gives rules that compare files the whole (filtered) file list
+func PrepareRules(rules []Rule, files []*FileInfo) {
+	for _, r := range rules {
+		if p, ok := r.(Preparer); ok {
+			p.Prepare(files)
+		}
+	}
+}
+
+// EvaluateRules runs every rule against info, in rule order
+func EvaluateRules(rules []Rule, info *FileInfo) []RuleFinding {
+	var findings []RuleFinding
+	for _, r := range rules {
+		if exp := r.Evaluate(info); exp != nil {
+			if info.PathCorrection != "" {
+				exp.Evidence = append(exp.Evidence, "Path corrected: "+info.PathCorrection)
+			}
+			findings = append(findings, RuleFinding{Rule: r, Explanation: exp})
+		}
+	}
+	return findings
+}
+
+// ruleHelp describes the registered rules for the --rules flag help
+func ruleHelp() string {
+	entries := append([]ruleEntry(nil), ruleRegistry...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].byDefault && !entries[j].byDefault })
+
+	var parts []string
+	for _, e := range entries {
+		part := e.id + " (" + e.description
+		if e.byDefault {
+			part += ", default"
+		}
+		parts = append(parts, part+")")
+	}
+	return strings.Join(parts, ", ")
+}
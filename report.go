@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats for --format
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Finding is one rule that fired for a file
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`
+	Reason   string   `json:"reason"`
+	Evidence []string `json:"evidence"`
+}
+
+// FileRecord is one flagged file in a machine-readable report:
+// the FileInfo fields plus every finding
+type FileRecord struct {
+	Path        string    `json:"path"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+	ModifiedAt  time.Time `json:"modified_at"`
+	AccessedAt  time.Time `json:"accessed_at"`
+	ChangedAt   time.Time `json:"changed_at"`
+	IsFile      bool      `json:"is_file"`
+	IsDirectory bool      `json:"is_directory"`
+	MimeType    string    `json:"mime_type"`
+	Findings    []Finding `json:"findings"`
+
+	// ParseWarnings lists metadata fields the server sent but we couldn't read
+	ParseWarnings []string `json:"parse_warnings,omitempty"`
+
+	// ListedPath and PathCorrection are only set when Path isn't exactly
+	// what list_directory returned
+	ListedPath     string `json:"listed_path,omitempty"`
+	PathCorrection string `json:"path_correction,omitempty"`
+
+	// DetectedMimeType is the type sniffed from the content (--sniff)
+	DetectedMimeType string `json:"detected_mime_type,omitempty"`
+}
+
+// ReportSummary holds the same counts PrintScanComplete shows
+type ReportSummary struct {
+	FilesScanned int `json:"files_scanned"`
+	FilesMatched int `json:"files_matched"`
+	FilesFlagged int `json:"files_flagged"`
+	ScanErrors   int `json:"scan_errors"`
+
+	// FindingsByRule counts flagged files per rule ID, every active rule
+	// is present (0 if it found nothing)
+	FindingsByRule map[string]int `json:"findings_by_rule"`
+
+	// ReclaimableBytesByRule is the size of the files each rule flagged,
+	// ReclaimableBytes that of all flagged files (each counted once).
+	// Informational rules (extension-mismatch) are left out of both.
+	ReclaimableBytesByRule map[string]int64 `json:"reclaimable_bytes_by_rule"`
+	ReclaimableBytes       int64            `json:"reclaimable_bytes"`
+}
+
+// Report is the full result of a scan
+type Report struct {
+	GeneratedAt time.Time            `json:"generated_at"`
+	Roots       []string             `json:"roots"`
+	Files       []FileRecord         `json:"files"`
+	Duplicates  []DuplicateSetRecord `json:"duplicate_sets,omitempty"`
+	Usage       *UsageReport         `json:"usage,omitempty"` // --usage
+	Summary     ReportSummary        `json:"summary"`
+}
+
+// DuplicateSetRecord is one set of identical files in a report
+type DuplicateSetRecord struct {
+	Hash             string   `json:"sha256"`
+	SizeBytes        int64    `json:"size_bytes"`
+	Keeper           string   `json:"keeper"`
+	Files            []string `json:"files"`
+	ReclaimableBytes int64    `json:"reclaimable_bytes"`
+}
+
+// AddDuplicateSets records the duplicate sets found by FindDuplicates
+func (r *Report) AddDuplicateSets(sets []*DuplicateSet) {
+	for _, set := range sets {
+		record := DuplicateSetRecord{
+			Hash:             set.Hash,
+			SizeBytes:        set.Size,
+			Keeper:           set.Keeper.Path,
+			ReclaimableBytes: set.Reclaimable(),
+		}
+		for _, f := range set.Files {
+			record.Files = append(record.Files, f.Path)
+		}
+		r.Duplicates = append(r.Duplicates, record)
+	}
+}
+
+// validFormat reports whether --format names a known format
+func validFormat(format string) bool {
+	switch format {
+	case FormatText, FormatJSON, FormatCSV, FormatNDJSON:
+		return true
+	}
+	return false
+}
+
+// Reclaimable adds up the size of the flagged files per rule and in total,
+// where a file two rules flagged counts once. Informational rules aren't
+// in either; every other active rule is in byRule (0 if it found nothing).
+func (r *Report) Reclaimable(rules []Rule) (byRule map[string]int64, total int64) {
+	byRule = map[string]int64{}
+	for _, rule := range rules {
+		if _, ok := rule.(Informational); !ok {
+			byRule[rule.ID()] = 0
+		}
+	}
+	for _, f := range r.Files {
+		reclaims := false
+		for _, finding := range f.Findings {
+			if _, ok := byRule[finding.Rule]; ok {
+				byRule[finding.Rule] += f.SizeBytes
+				reclaims = true
+			}
+		}
+		if reclaims {
+			total += f.SizeBytes
+		}
+	}
+	return byRule, total
+}
+
+// AddFile records a flagged file with its findings
+func (r *Report) AddFile(info *FileInfo, ruleFindings []RuleFinding) {
+	findings := make([]Finding, 0, len(ruleFindings))
+	for _, f := range ruleFindings {
+		findings = append(findings, Finding{
+			Rule:     f.Rule.ID(),
+			Severity: f.Rule.Severity().String(),
+			Reason:   f.Explanation.Reason,
+			Evidence: f.Explanation.Evidence,
+		})
+	}
+
+	record := FileRecord{
+		Path:        info.Path,
+		SizeBytes:   info.SizeBytes,
+		CreatedAt:   info.CreatedAt,
+		ModifiedAt:  info.ModifiedAt,
+		AccessedAt:  info.AccessedAt,
+		ChangedAt:   info.ChangedAt,
+		IsFile:      info.IsFile,
+		IsDirectory: info.IsDirectory,
+		MimeType:    info.MimeType,
+		Findings:    findings,
+
+		ParseWarnings:    parseWarningStrings(info.ParseWarnings),
+		DetectedMimeType: info.DetectedMimeType,
+	}
+	if !info.ListedVerbatim() {
+		record.ListedPath = info.ListedPath
+		record.PathCorrection = info.PathCorrection
+	}
+	r.Files = append(r.Files, record)
+}
+
+func parseWarningStrings(warnings []ParseWarning) []string {
+	var out []string
+	for _, w := range warnings {
+		out = append(out, w.Error())
+	}
+	return out
+}
+
+// WriteReport writes the report in one of the machine-readable formats
+func WriteReport(w io.Writer, format string, report *Report) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+
+	case FormatNDJSON:
+		return writeNDJSON(w, report)
+
+	case FormatCSV:
+		return writeCSV(w, report)
+
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// writeNDJSON writes one {"type":"file",...} line per flagged file, one
+// {"type":"duplicate_set",...} line per duplicate set, a {"type":"usage",...}
+// line with --usage and a final {"type":"summary",...} line, so consumers
+// can stream it
+func writeNDJSON(w io.Writer, report *Report) error {
+	enc := json.NewEncoder(w)
+
+	for _, f := range report.Files {
+		line := struct {
+			Type string `json:"type"`
+			FileRecord
+		}{"file", f}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range report.Duplicates {
+		line := struct {
+			Type string `json:"type"`
+			DuplicateSetRecord
+		}{"duplicate_set", d}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	if report.Usage != nil {
+		line := struct {
+			Type string `json:"type"`
+			*UsageReport
+		}{"usage", report.Usage}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	summary := struct {
+		Type string `json:"type"`
+		ReportSummary
+	}{"summary", report.Summary}
+	return enc.Encode(summary)
+}
+
+// writeCSV writes one row per finding (a file flagged by two rules gets two
+// rows), which is what spreadsheets filter on best.
+// Evidence lines are joined with " | ". CSV has no place for the summary
+// or --usage (main rejects that combination), use json/ndjson for those.
+func writeCSV(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"path", "size_bytes", "created_at", "modified_at", "accessed_at", "changed_at",
+		"is_file", "is_directory", "mime_type", "rule", "severity", "reason", "evidence",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, f := range report.Files {
+		for _, finding := range f.Findings {
+			row := []string{
+				f.Path,
+				strconv.FormatInt(f.SizeBytes, 10),
+				formatReportTime(f.CreatedAt),
+				formatReportTime(f.ModifiedAt),
+				formatReportTime(f.AccessedAt),
+				formatReportTime(f.ChangedAt),
+				strconv.FormatBool(f.IsFile),
+				strconv.FormatBool(f.IsDirectory),
+				f.MimeType,
+				finding.Rule,
+				finding.Severity,
+				finding.Reason,
+				strings.Join(finding.Evidence, " | "),
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatReportTime leaves unknown timestamps empty instead of year 0001
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
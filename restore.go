@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RestoreSelector picks which history records a selective undo restores.
+// Every criterion that is set must match (they AND together).
+type RestoreSelector struct {
+	// Indexes are 1-based, as printed by ShowHistory
+	Indexes []int
+
+	// Glob is matched against the filename and the original path
+	Glob string
+
+	// Since selects files deleted at or after this time
+	Since time.Time
+
+	// Session selects one deletion session ("last" = most recent one)
+	Session string
+}
+
+// IsEmpty reports whether no criterion was given
+func (s RestoreSelector) IsEmpty() bool {
+	return len(s.Indexes) == 0 && s.Glob == "" && s.Since.IsZero() && s.Session == ""
+}
+
+// RestoreResult is the outcome of restoring one history record
+type RestoreResult struct {
+	Record DeletionRecord
+	Err    error
+}
+
+// SelectRecords returns the positions in history.Records matching sel
+func SelectRecords(history *DeletionHistory, sel RestoreSelector) ([]int, error) {
+	wanted := map[int]bool{}
+	for _, idx := range sel.Indexes {
+		if idx < 1 || idx > len(history.Records) {
+			return nil, fmt.Errorf("no history entry #%d (history has %d entries)", idx, len(history.Records))
+		}
+		wanted[idx-1] = true
+	}
+
+	if sel.Glob != "" {
+		if _, err := filepath.Match(sel.Glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", sel.Glob, err)
+		}
+	}
+
+	session := sel.Session
+	if session == "last" {
+		session = lastSession(history)
+		if session == "" {
+			return nil, fmt.Errorf("no deletion sessions recorded")
+		}
+	}
+
+	var selected []int
+	for i, record := range history.Records {
+		if len(wanted) > 0 && !wanted[i] {
+			continue
+		}
+		if sel.Glob != "" && !matchRecordGlob(record, sel.Glob) {
+			continue
+		}
+		if !sel.Since.IsZero() && record.DeletedAt.Before(sel.Since) {
+			continue
+		}
+		if session != "" && record.SessionID != session {
+			continue
+		}
+		selected = append(selected, i)
+	}
+	return selected, nil
+}
+
+// RestoreRecords restores the given records (positions from SelectRecords)
+// one by one and drops the restored ones from history.
+// Failures stay in history so they can be retried.
+func RestoreRecords(history *DeletionHistory, indexes []int) []RestoreResult {
+	results := make([]RestoreResult, 0, len(indexes))
+	restored := map[int]bool{}
+
+	for _, i := range indexes {
+		record := history.Records[i]
+		err := RestoreRecord(record)
+		if err == nil {
+			restored[i] = true
+		}
+		results = append(results, RestoreResult{Record: record, Err: err})
+	}
+
+	remaining := history.Records[:0]
+	for i, record := range history.Records {
+		if !restored[i] {
+			remaining = append(remaining, record)
+		}
+	}
+	history.Records = remaining
+
+	return results
+}
+
+// matchRecordGlob matches the pattern against the filename, then the full path
+func matchRecordGlob(record DeletionRecord, pattern string) bool {
+	if ok, _ := filepath.Match(pattern, record.FileName); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, record.OrigionalFilePath)
+	return ok
+}
+
+// lastSession returns the session ID of the most recent deletion
+func lastSession(history *DeletionHistory) string {
+	for i := len(history.Records) - 1; i >= 0; i-- {
+		if history.Records[i].SessionID != "" {
+			return history.Records[i].SessionID
+		}
+	}
+	return ""
+}
+
+// newSessionID names one --delete run, e.g. "20260118-153012.482-7311".
+// Milliseconds and the pid keep two runs in the same second apart, one
+// --undo-session must not restore both.
+func newSessionID() string {
+	return fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405.000"), os.Getpid())
+}
+
+// parseIndexList parses "3,7,12" or "3-5" style index lists of a history
+// with count entries. Every index is checked before a range is expanded,
+// "1-2000000000" is an error and not two billion ints.
+func parseIndexList(s string, count int) ([]int, error) {
+	var indexes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil || end < start {
+				return nil, fmt.Errorf("invalid index range %q", part)
+			}
+		}
+		for _, idx := range []int{start, end} {
+			if idx < 1 || idx > count {
+				return nil, fmt.Errorf("no history entry #%d (history has %d entries)", idx, count)
+			}
+		}
+		for i := start; i <= end; i++ {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// parseSinceTime accepts RFC3339 or the local "2006-01-02 15:04:05" style
+// timestamps ShowHistory prints (seconds and time are optional)
+func parseSinceTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use 2006-01-02 15:04:05 or RFC3339)", s)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIndexList(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string // indexes, or "error"
+	}{
+		{"3", "[3]"},
+		{"3,7,1", "[1 3 7]"},
+		{"3-5", "[3 4 5]"},
+		{" 2 - 3 , 9 ", "[2 3 9]"},
+		{"4,,", "[4]"},
+		{"5-5", "[5]"},
+		{"10", "[10]"},
+		{"11", "error"}, // past the end
+		{"0", "error"},
+		{"5-3", "error"},
+		{"1-2000000000", "error"}, // not expanded first
+		{"-3", "error"},
+		{"x", "error"},
+		{"1-x", "error"},
+	}
+	for _, tt := range tests {
+		indexes, err := parseIndexList(tt.spec, 10)
+		got := fmt.Sprint(indexes)
+		if err != nil {
+			got = "error"
+		}
+		if got != tt.want {
+			t.Errorf("parseIndexList(%q) = %s (%v), want %s", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSinceTime(t *testing.T) {
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"2026-01-18T15:30:12Z", time.Date(2026, 1, 18, 15, 30, 12, 0, time.UTC)},
+		{"2026-01-18T15:30:12+02:00", time.Date(2026, 1, 18, 13, 30, 12, 0, time.UTC)},
+		{"2026-01-18 15:30:12", time.Date(2026, 1, 18, 15, 30, 12, 0, time.Local)},
+		{" 2026-01-18 15:30 ", time.Date(2026, 1, 18, 15, 30, 0, 0, time.Local)},
+		{"2026-01-18T15:30", time.Date(2026, 1, 18, 15, 30, 0, 0, time.Local)},
+		{"2026-01-18", time.Date(2026, 1, 18, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parseSinceTime(tt.spec)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseSinceTime(%q) = %v, %v; want %v", tt.spec, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "yesterday", "18.01.2026", "2026-13-01"} {
+		if _, err := parseSinceTime(bad); err == nil {
+			t.Errorf("parseSinceTime(%q) accepted it", bad)
+		}
+	}
+}
+
+func TestSelectRecords(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.UTC) }
+	history := &DeletionHistory{Records: []DeletionRecord{
+		{FileName: "a.pdf", OrigionalFilePath: "/docs/a.pdf", DeletedAt: day(1), SessionID: "s1"},
+		{FileName: "b.txt", OrigionalFilePath: "/docs/b.txt", DeletedAt: day(1), SessionID: "s1"},
+		{FileName: "c.pdf", OrigionalFilePath: "/tmp/c.pdf", DeletedAt: day(5), SessionID: "s2"},
+		{FileName: "d.log", OrigionalFilePath: "/tmp/d.log", DeletedAt: day(9)}, // from before sessions
+		{FileName: "e.pdf", OrigionalFilePath: "/docs/e.pdf", DeletedAt: day(9), SessionID: "s3"},
+	}}
+
+	tests := []struct {
+		name string
+		sel  RestoreSelector
+		want string
+	}{
+		{"indexes", RestoreSelector{Indexes: []int{2, 5}}, "[1 4]"},
+		{"glob on the name", RestoreSelector{Glob: "*.pdf"}, "[0 2 4]"},
+		{"glob on the path", RestoreSelector{Glob: "/tmp/*"}, "[2 3]"},
+		{"since", RestoreSelector{Since: day(5)}, "[2 3 4]"},
+		{"session", RestoreSelector{Session: "s1"}, "[0 1]"},
+		{"last session", RestoreSelector{Session: "last"}, "[4]"},
+		{"unknown session", RestoreSelector{Session: "nope"}, "[]"},
+		{"criteria AND together", RestoreSelector{Glob: "*.pdf", Since: day(5)}, "[2 4]"},
+		{"indexes AND glob", RestoreSelector{Indexes: []int{1, 2, 3}, Glob: "*.pdf"}, "[0 2]"},
+	}
+	for _, tt := range tests {
+		selected, err := SelectRecords(history, tt.sel)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := fmt.Sprint(selected); got != tt.want {
+			t.Errorf("%s: selected %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []RestoreSelector{{Indexes: []int{6}}, {Glob: "[a"}} {
+		if _, err := SelectRecords(history, bad); err == nil {
+			t.Errorf("SelectRecords(%+v) accepted it", bad)
+		}
+	}
+	if _, err := SelectRecords(&DeletionHistory{}, RestoreSelector{Session: "last"}); err == nil {
+		t.Error("last session of an empty history")
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+	// two runs in the same second differ by milliseconds or pid
+	id := newSessionID()
+	stamp, pid, ok := strings.Cut(id, "-"+fmt.Sprint(os.Getpid()))
+	if !ok || pid != "" {
+		t.Fatalf("session %q doesn't end in the pid", id)
+	}
+	if _, err := time.ParseInLocation("20060102-150405.000", stamp, time.Local); err != nil {
+		t.Errorf("session %q: %v", id, err)
+	}
+}
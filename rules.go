@@ -2,8 +2,8 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
-	// "strings"
 )
 
 // func IsLikelyUnused(info *FileInfo, days int) bool {
@@ -15,24 +15,162 @@ import (
 // 	return time.Since(info.AccessedAt) > threshold
 // }
 
-func ExplainUnused(info *FileInfo, days int) *Explanation {
+func init() {
+	RegisterRule("unused", "old files, see --unused-days/--age-basis", true, func() Rule {
+		return &UnusedRule{Days: unusedDays, Basis: ageBasis}
+	})
+	RegisterRule("zero-byte", "empty files", true, func() Rule {
+		return &ZeroByteRule{}
+	})
+	RegisterRule("duplicate", "identical content, see --keep", false, func() Rule {
+		return &DuplicateRule{Keep: keepPolicy}
+	})
+	RegisterRule("extension-mismatch", "extension doesn't match the content, implies --sniff", false, func() Rule {
+		return &ExtensionMismatchRule{}
+	})
+}
+
+// UnusedRule wraps ExplainUnused
+type UnusedRule struct {
+	Days  int
+	Basis AgeBasis
+}
+
+func (r *UnusedRule) ID() string         { return "unused" }
+func (r *UnusedRule) Severity() Severity { return SeverityWarning }
+func (r *UnusedRule) Evaluate(info *FileInfo) *Explanation {
+	return ExplainUnused(info, r.Days, r.Basis)
+}
+
+// ZeroByteRule wraps ExplainZeroByte
+type ZeroByteRule struct{}
+
+func (r *ZeroByteRule) ID() string         { return "zero-byte" }
+func (r *ZeroByteRule) Severity() Severity { return SeverityInfo }
+func (r *ZeroByteRule) Evaluate(info *FileInfo) *Explanation {
+	return ExplainZeroByte(info)
+}
+
+// DuplicateRule hashes every file in Prepare, then wraps ExplainDuplicate
+type DuplicateRule struct {
+	Keep  KeepPolicy
+	Sets  []*DuplicateSet
+	index DuplicateIndex
+}
+
+func (r *DuplicateRule) ID() string         { return "duplicate" }
+func (r *DuplicateRule) Severity() Severity { return SeverityWarning }
+
+func (r *DuplicateRule) Prepare(files []*FileInfo) {
+	r.Sets = FindDuplicates(files, r.Keep)
+	r.index = NewDuplicateIndex(r.Sets)
+}
+
+func (r *DuplicateRule) Evaluate(info *FileInfo) *Explanation {
+	return ExplainDuplicate(info, r.index)
+}
+
+// Summarize lists the sets with their keepers after the per-file findings
+func (r *DuplicateRule) Summarize(report *Report) {
+	if len(r.Sets) == 0 {
+		return
+	}
+	PrintDuplicateSets(r.Sets)
+	report.AddDuplicateSets(r.Sets)
+}
+
+// ExtensionMismatchRule wraps ExplainExtensionMismatch
+type ExtensionMismatchRule struct{}
+
+func (r *ExtensionMismatchRule) ID() string         { return "extension-mismatch" }
+func (r *ExtensionMismatchRule) Severity() Severity { return SeverityWarning }
+func (r *ExtensionMismatchRule) Evaluate(info *FileInfo) *Explanation {
+	return ExplainExtensionMismatch(info)
+}
+
+// a renamed file is a file to fix, not one to delete
+func (r *ExtensionMismatchRule) Informational() {}
+
+// AgeBasis picks which timestamp ExplainUnused measures a file's age by
+type AgeBasis string
+
+const (
+	AgeAtime  AgeBasis = "atime"  // last access
+	AgeMtime  AgeBasis = "mtime"  // last content modification
+	AgeCtime  AgeBasis = "ctime"  // last status change (creation time on Windows)
+	AgeNewest AgeBasis = "newest" // whichever of the three is most recent
+)
+
+func parseAgeBasis(s string) (AgeBasis, error) {
+	switch b := AgeBasis(strings.ToLower(s)); b {
+	case AgeAtime, AgeMtime, AgeCtime, AgeNewest:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown age basis %q (use atime, mtime, ctime or newest)", s)
+}
+
+// usesAtime reports whether the basis depends on access times,
+// which noatime/relatime mounts make unreliable
+func (b AgeBasis) usesAtime() bool {
+	return b == AgeAtime || b == AgeNewest
+}
+
+// timestamp returns the time the basis picks for info and a label for it
+func (b AgeBasis) timestamp(info *FileInfo) (time.Time, string) {
+	switch b {
+	case AgeAtime:
+		return info.AccessedAt, "Last accessed"
+	case AgeCtime:
+		return info.ChangedAt, "Last changed"
+	case AgeNewest:
+		newest, label := info.AccessedAt, "Last accessed"
+		if info.ModifiedAt.After(newest) {
+			newest, label = info.ModifiedAt, "Last modified"
+		}
+		if info.ChangedAt.After(newest) {
+			newest, label = info.ChangedAt, "Last changed"
+		}
+		return newest, label
+	default:
+		return info.ModifiedAt, "Last modified"
+	}
+}
+
+// describe is the "Not ... in last N days" wording for the basis
+func (b AgeBasis) describe() string {
+	switch b {
+	case AgeAtime:
+		return "Not accessed"
+	case AgeCtime:
+		return "Not changed"
+	case AgeNewest:
+		return "Not accessed, modified or changed"
+	default:
+		return "Not modified"
+	}
+}
+
+// ExplainUnused flags files whose chosen timestamp (see AgeBasis) is older
+// than days. Files with an unknown timestamp are never flagged.
+func ExplainUnused(info *FileInfo, days int, basis AgeBasis) *Explanation {
 	if info.IsDirectory {
 		return nil
 	}
 
-	if info.AccessedAt.IsZero() {
+	ts, label := basis.timestamp(info)
+	if ts.IsZero() {
 		return nil
 	}
 
-	if time.Since(info.ModifiedAt) < time.Duration(days)*24*time.Hour {
+	if time.Since(ts) < time.Duration(days)*24*time.Hour {
 		return nil
 	}
 
 	return &Explanation{
-		Reason: "File apprears unused",
+		Reason: "File appears unused",
 		Evidence: []string{
-			fmt.Sprintf("Not accessed in last %d days", days),
-			fmt.Sprintf("Last modified: %s", info.ModifiedAt.Format("2006-01-02")),
+			fmt.Sprintf("%s in last %d days (based on %s)", basis.describe(), days, basis),
+			fmt.Sprintf("%s: %s", label, ts.Format("2006-01-02")),
 			fmt.Sprintf("Size: %d bytes", info.SizeBytes),
 		},
 	}
@@ -44,19 +182,21 @@ func ExplainZeroByte(info *FileInfo) *Explanation {
 		return nil
 	}
 
-	// Get the REAL file size (works with OneDrive)
-	realSize, err := GetRealFileSize(info.Path)
-	if err == nil && realSize > 0 {
+	// The local size is the REAL one (works with OneDrive). Files we can't
+	// stat locally (file gone) and remote ones go by the server's size.
+	local := localPath(info.Path)
+	size := info.SizeBytes
+	if !info.Remote {
+		if realSize, err := GetRealFileSize(local); err == nil {
+			size = realSize
+		}
+	}
+	if size > 0 {
 		return nil // File actually has content
 	}
 
 	// Also skip if it's a cloud placeholder (size is in cloud)
-	if IsCloudPlaceholder(info.Path) {
-		return nil
-	}
-
-	// If we get here, file is genuinely 0 bytes
-	if info.SizeBytes != 0 && realSize != 0 {
+	if !info.Remote && IsCloudPlaceholder(local) {
 		return nil
 	}
 
@@ -68,3 +208,72 @@ func ExplainZeroByte(info *FileInfo) *Explanation {
 		},
 	}
 }
+
+// ExplainDuplicate flags every member of a duplicate set except the keeper,
+// so only the redundant copies show up (and get offered in --delete)
+func ExplainDuplicate(info *FileInfo, dups DuplicateIndex) *Explanation {
+	if info.IsDirectory {
+		return nil
+	}
+
+	set, ok := dups[info.Path]
+	if !ok || set.Keeper.Path == info.Path {
+		return nil
+	}
+
+	return &Explanation{
+		Reason: "File is a duplicate",
+		Evidence: []string{
+			fmt.Sprintf("Same content as %s (keeping the %s copy)", set.Keeper.Path, set.Keep),
+			fmt.Sprintf("%d identical copies of %s", len(set.Files), formatFileSize(set.Size)),
+			fmt.Sprintf("SHA-256: %s", set.Hash),
+		},
+	}
+}
+
+// ExplainExtensionMismatch flags files whose sniffed content type doesn't
+// fit their extension, e.g. an executable named invoice.pdf. Only extensions
+// that promise another type count (another signature's or one in the type
+// catalog), so "libfoo.so.1" or "data.bak" are never flagged.
+func ExplainExtensionMismatch(info *FileInfo) *Explanation {
+	if info.IsDirectory || info.DetectedMimeType == "" {
+		return nil
+	}
+
+	ext := strings.ToLower(getExtension(getFileName(info.Path)))
+	expected, known := sniffedExtensions[info.DetectedMimeType]
+	if ext == "" || !known {
+		return nil
+	}
+	for _, e := range expected {
+		if e == ext {
+			return nil
+		}
+	}
+	if !typeCatalog.HasExtension(ext) && !isSniffedExtension(ext) {
+		return nil
+	}
+
+	evidence := []string{
+		fmt.Sprintf("Named %s, but the content is %s", ext, info.DetectedMimeType),
+		"Expected extension: " + strings.Join(expected, ", "),
+	}
+	if info.MimeType != "" && info.MimeType != info.DetectedMimeType {
+		evidence = append(evidence, "Server reported: "+info.MimeType)
+	}
+	return &Explanation{
+		Reason:   "Extension does not match content",
+		Evidence: evidence,
+	}
+}
+
+func isSniffedExtension(ext string) bool {
+	for _, exts := range sniffedExtensions {
+		for _, e := range exts {
+			if e == ext {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExplainZeroByte(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.log")
+	full := filepath.Join(dir, "full.txt")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	remote := "/srv/remote-only/" + filepath.Base(dir) + "/report.txt" // not on this machine
+
+	tests := []struct {
+		name    string
+		info    FileInfo
+		flagged bool
+	}{
+		{"local empty file", FileInfo{Path: empty, IsFile: true}, true},
+		{"local file the server says is empty", FileInfo{Path: full, IsFile: true}, false},
+		{"remote file with content", FileInfo{Path: remote, SizeBytes: 68, IsFile: true}, false},
+		{"remote empty file", FileInfo{Path: remote, IsFile: true}, true},
+		{"directory", FileInfo{Path: dir, IsDirectory: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExplainZeroByte(&tt.info) != nil; got != tt.flagged {
+				t.Errorf("flagged = %v, want %v", got, tt.flagged)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultServerCommand is used when neither the config file nor --server
+// names a server
+const defaultServerCommand = "mcp-filesystem-server"
+
+// ServerConfig says how to start (or reach) the MCP filesystem server.
+//
+// A local server is run as: Command Args... Roots... scan paths...
+// which fits mcp-filesystem-server and @modelcontextprotocol/server-filesystem,
+// both take the allowed directories as trailing arguments.
+//
+// With URL set the server is remote (Streamable HTTP) and the local
+// settings are ignored; the remote side decides what it may access.
+type ServerConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`   // added to our own environment
+	Dir     string            `json:"dir,omitempty"`   // working directory, "" = ours
+	Roots   []string          `json:"roots,omitempty"` // allowed directories besides the scan paths
+
+	URL            string            `json:"url,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	BearerToken    string            `json:"bearer_token,omitempty"`
+	BearerTokenEnv string            `json:"bearer_token_env,omitempty"` // read the token from this variable instead
+}
+
+// LoadServerConfig reads a JSON server config file, e.g.
+//
+//	{
+//	  "command": "npx",
+//	  "args": ["-y", "@modelcontextprotocol/server-filesystem"],
+//	  "env": {"NODE_OPTIONS": "--max-old-space-size=4096"},
+//	  "roots": ["/srv/share"]
+//	}
+//
+// or, for a remote server:
+//
+//	{"url": "https://files.example.com/mcp", "bearer_token_env": "MCP_TOKEN"}
+//
+// A relative command path (one with a separator), dir and roots are taken
+// relative to the config file.
+func LoadServerConfig(path string) (*ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &ServerConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid server config %s: %v", path, err)
+	}
+
+	base := filepath.Dir(path)
+	if strings.ContainsRune(cfg.Command, filepath.Separator) && !filepath.IsAbs(cfg.Command) {
+		cfg.Command = filepath.Join(base, cfg.Command)
+	}
+	if cfg.Dir != "" && !filepath.IsAbs(cfg.Dir) {
+		cfg.Dir = filepath.Join(base, cfg.Dir)
+	}
+	for i, root := range cfg.Roots {
+		if !filepath.IsAbs(root) {
+			cfg.Roots[i] = filepath.Join(base, root)
+		}
+	}
+	return cfg, nil
+}
+
+// DefaultServerConfigPath is where the config is looked for when
+// --server-config isn't given
+func DefaultServerConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "filesystem-analyzer", "server.json")
+}
+
+// resolveServerConfig merges, lowest priority first: the built-in default,
+// the config file (--server-config, or the default path if it exists) and
+// the --server* / --root flags
+func resolveServerConfig() (*ServerConfig, error) {
+	cfg := &ServerConfig{}
+
+	path := serverConfigPath
+	if path == "" {
+		if def := DefaultServerConfigPath(); def != "" {
+			if _, err := os.Stat(def); err == nil {
+				path = def
+			}
+		}
+	}
+	if path != "" {
+		loaded, err := LoadServerConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+
+	if serverCommand != "" {
+		cfg.Command = serverCommand
+	}
+	if cfg.Command == "" {
+		cfg.Command = defaultServerCommand
+	}
+	cfg.Args = append(cfg.Args, serverArgs...)
+	if serverURL != "" {
+		cfg.URL = serverURL
+	}
+	if serverToken != "" {
+		cfg.BearerToken = serverToken
+	}
+	for _, h := range serverHeaders {
+		key, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --server-header %q (use 'Name: value')", h)
+		}
+		if cfg.Headers == nil {
+			cfg.Headers = map[string]string{}
+		}
+		cfg.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if serverDir != "" {
+		cfg.Dir = serverDir
+	}
+
+	for _, kv := range serverEnv {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --server-env %q (use KEY=VALUE)", kv)
+		}
+		if cfg.Env == nil {
+			cfg.Env = map[string]string{}
+		}
+		cfg.Env[key] = value
+	}
+
+	for _, root := range serverRoots {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		cfg.Roots = append(cfg.Roots, root)
+	}
+	return cfg, nil
+}
+
+// commandArgs is the server's argument list with every allowed directory
+// (configured roots first, then the scan paths) appended once
+func (cfg *ServerConfig) commandArgs(scanPaths []string) []string {
+	args := append([]string(nil), cfg.Args...)
+	seen := map[string]bool{}
+	for _, root := range append(append([]string(nil), cfg.Roots...), scanPaths...) {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		args = append(args, root)
+	}
+	return args
+}
+
+// environ is our environment plus the configured variables, sorted so the
+// child sees them in a stable order
+func (cfg *ServerConfig) environ() []string {
+	if len(cfg.Env) == 0 {
+		return nil // exec.Cmd then inherits ours
+	}
+	keys := make([]string, 0, len(cfg.Env))
+	for key := range cfg.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := os.Environ()
+	for _, key := range keys {
+		env = append(env, key+"="+cfg.Env[key])
+	}
+	return env
+}
+
+// httpHeaders are the extra headers sent to a remote server, including
+// Authorization when a bearer token is configured
+func (cfg *ServerConfig) httpHeaders() map[string]string {
+	headers := map[string]string{}
+	for key, value := range cfg.Headers {
+		headers[key] = value
+	}
+
+	token := cfg.BearerToken
+	if token == "" && cfg.BearerTokenEnv != "" {
+		token = os.Getenv(cfg.BearerTokenEnv)
+	}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return headers
+}
+
+// String is the command line (or URL) for messages, never the token
+func (cfg *ServerConfig) String() string {
+	if cfg.URL != "" {
+		return cfg.URL
+	}
+	return strings.TrimSpace(cfg.Command + " " + strings.Join(cfg.Args, " "))
+}
+
+// errServerStart explains a missing server binary instead of a bare
+// "executable file not found"
+func errServerStart(cfg *ServerConfig, err error) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("MCP server %q not found (install it, or point --server / \"command\" in %s at it)",
+			cfg.Command, configHint())
+	}
+	return fmt.Errorf("failed to start MCP server %q: %v", cfg.Command, err)
+}
+
+func configHint() string {
+	if serverConfigPath != "" {
+		return serverConfigPath
+	}
+	if def := DefaultServerConfigPath(); def != "" {
+		return def
+	}
+	return "the --server-config file"
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+)
+
+// sniffSize is how much of a file is read to recognize it. PE headers
+// usually start within the first few hundred bytes.
+const sniffSize = 4096
+
+// MIME types detected from content that need a name here
+const (
+	mimeZip  = "application/zip"
+	mimeDocx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimePptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+// signature recognizes one file type by its first bytes
+type signature struct {
+	mime  string
+	match func(head []byte) bool
+}
+
+func magic(prefixes ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(head, []byte(p)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var signatures = []signature{
+	{"application/pdf", magic("%PDF-")},
+	{"image/png", magic("\x89PNG\r\n\x1a\n")},
+	{"image/jpeg", magic("\xff\xd8\xff")},
+	{"image/gif", magic("GIF87a", "GIF89a")},
+	{mimeZip, magic("PK\x03\x04", "PK\x05\x06")},
+	{"application/x-elf", magic("\x7fELF")},
+	{"application/vnd.microsoft.portable-executable", isPE},
+	{"application/gzip", magic("\x1f\x8b")},
+	{"application/x-xz", magic("\xfd7zXZ\x00")},
+	{"application/zstd", magic("\x28\xb5\x2f\xfd")},
+	{"application/vnd.sqlite3", magic("SQLite format 3\x00")},
+}
+
+// sniffedExtensions are the extensions a file of each detected type may
+// carry; anything else is an extension mismatch
+var sniffedExtensions = map[string][]string{
+	"application/pdf": {".pdf", ".ai"},
+	"image/png":       {".png"},
+	"image/jpeg":      {".jpg", ".jpeg", ".jpe", ".jfif"},
+	"image/gif":       {".gif"},
+	mimeZip:           {".zip", ".jar", ".war", ".ear", ".apk", ".aar", ".xpi", ".whl", ".nupkg", ".vsix", ".ipa", ".kmz", ".cbz"},
+	mimeDocx:          {".docx", ".docm", ".dotx", ".dotm"},
+	mimeXlsx:          {".xlsx", ".xlsm", ".xltx", ".xltm"},
+	mimePptx:          {".pptx", ".pptm", ".ppsx", ".potx"},
+	"application/vnd.oasis.opendocument.text":         {".odt", ".ott"},
+	"application/vnd.oasis.opendocument.spreadsheet":  {".ods", ".ots"},
+	"application/vnd.oasis.opendocument.presentation": {".odp", ".otp"},
+	"application/vnd.oasis.opendocument.graphics":     {".odg", ".otg"},
+	"application/epub+zip":                            {".epub"},
+	"application/x-elf":                               {".so", ".o", ".ko", ".elf", ".bin", ".out", ".axf", ".run"},
+	"application/vnd.microsoft.portable-executable":   {".exe", ".dll", ".sys", ".scr", ".cpl", ".ocx", ".efi", ".mui", ".drv", ".com"},
+	"application/gzip":                                {".gz", ".tgz", ".svgz"},
+	"application/x-xz":                                {".xz", ".txz"},
+	"application/zstd":                                {".zst", ".tzst", ".zstd"},
+	"application/vnd.sqlite3":                         {".sqlite", ".sqlite3", ".db", ".db3", ".s3db", ".sl3"},
+}
+
+// isPE checks the "MZ" DOS header and the "PE\0\0" it points to, plain
+// "MZ" is too common a start for text
+func isPE(head []byte) bool {
+	if len(head) < 0x40 || !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+	offset := int(binary.LittleEndian.Uint32(head[0x3c:]))
+	return offset+4 <= len(head) && bytes.Equal(head[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+// SniffMimeType reads the start of a local file and returns its type from
+// magic numbers, "" when it isn't one we know. Zip files are looked into to
+// tell Office (OOXML), OpenDocument and EPUB files from plain archives.
+func SniffMimeType(path string) (string, error) {
+	f, err := os.Open(localPath(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	head = head[:n]
+
+	for _, sig := range signatures {
+		if !sig.match(head) {
+			continue
+		}
+		if sig.mime == mimeZip {
+			if inner := zipMimeType(f, head); inner != "" {
+				return inner, nil
+			}
+		}
+		return sig.mime, nil
+	}
+	return "", nil
+}
+
+// zipMimeType recognizes the zip based formats: OpenDocument and EPUB
+// start with a stored "mimetype" entry naming the type, OOXML files have
+// word/, xl/ or ppt/ directories
+func zipMimeType(f *os.File, head []byte) string {
+	// local file header: name length at 26, extra length at 28, sizes at
+	// 18 (compressed), the name at 30, then the data
+	if len(head) >= 30 && binary.LittleEndian.Uint16(head[8:]) == 0 {
+		nameLen := int(binary.LittleEndian.Uint16(head[26:]))
+		extraLen := int(binary.LittleEndian.Uint16(head[28:]))
+		size := int(binary.LittleEndian.Uint32(head[18:]))
+		start := 30 + nameLen + extraLen
+		if 30+nameLen <= len(head) && string(head[30:30+nameLen]) == "mimetype" &&
+			size > 0 && size < 128 && start+size <= len(head) {
+			return strings.TrimSpace(string(head[start : start+size]))
+		}
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	zr, err := zip.NewReader(f, st.Size())
+	if err != nil {
+		return ""
+	}
+	for _, file := range zr.File {
+		switch {
+		case strings.HasPrefix(file.Name, "word/"):
+			return mimeDocx
+		case strings.HasPrefix(file.Name, "xl/"):
+			return mimeXlsx
+		case strings.HasPrefix(file.Name, "ppt/"):
+			return mimePptx
+		}
+	}
+	return ""
+}
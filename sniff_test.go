@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipBytes builds a zip with the given entries, in order. A "mimetype"
+// entry is stored uncompressed with its sizes in the local header, the
+// way OpenDocument and EPUB writers do it.
+func zipBytes(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		name, content, _ := strings.Cut(entry, "=")
+		var w io.Writer
+		var err error
+		if name == "mimetype" {
+			w, err = zw.CreateRaw(&zip.FileHeader{
+				Name:               name,
+				Method:             zip.Store,
+				CRC32:              crc32.ChecksumIEEE([]byte(content)),
+				CompressedSize64:   uint64(len(content)),
+				UncompressedSize64: uint64(len(content)),
+			})
+		} else {
+			w, err = zw.Create(name)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// peBytes is the smallest header isPE accepts
+func peBytes() []byte {
+	head := make([]byte, 0x80)
+	copy(head, "MZ")
+	binary.LittleEndian.PutUint32(head[0x3c:], 0x40)
+	copy(head[0x40:], "PE\x00\x00")
+	return head
+}
+
+func TestSniffMimeType(t *testing.T) {
+	notPE := peBytes()
+	copy(notPE[0x40:], "XX")
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"doc.pdf", []byte("%PDF-1.7\n..."), "application/pdf"},
+		{"photo.pdf", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"}, // renamed PNG
+		{"a.jpg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), "image/jpeg"},
+		{"a.gif", []byte("GIF89a\x01\x00"), "image/gif"},
+		{"report.docx", zipBytes(t, "[Content_Types].xml=<Types/>", "word/document.xml=<w:document/>"), mimeDocx},
+		{"sheet.xlsx", zipBytes(t, "[Content_Types].xml=<Types/>", "xl/workbook.xml=<workbook/>"), mimeXlsx},
+		{"deck.pptx", zipBytes(t, "[Content_Types].xml=<Types/>", "ppt/presentation.xml=<p/>"), mimePptx},
+		{"letter.odt", zipBytes(t, "mimetype=application/vnd.oasis.opendocument.text", "content.xml=<office/>"),
+			"application/vnd.oasis.opendocument.text"},
+		{"book.epub", zipBytes(t, "mimetype=application/epub+zip", "META-INF/container.xml=<c/>"), "application/epub+zip"},
+		{"plain.zip", zipBytes(t, "a.txt=hello", "b/c.txt=world"), mimeZip},
+		{"empty.zip", zipBytes(t), mimeZip},
+		{"setup.exe", peBytes(), "application/vnd.microsoft.portable-executable"},
+		{"notes.txt", notPE, ""}, // "MZ" without a PE header
+		{"MZ.txt", []byte("MZ is where it starts"), ""},
+		{"lib.so", []byte("\x7fELF\x02\x01\x01"), "application/x-elf"},
+		{"a.gz", []byte("\x1f\x8b\x08\x00"), "application/gzip"},
+		{"a.xz", []byte("\xfd7zXZ\x00\x00"), "application/x-xz"},
+		{"a.zst", []byte("\x28\xb5\x2f\xfd\x00"), "application/zstd"},
+		{"a.db", []byte("SQLite format 3\x00\x10\x00"), "application/vnd.sqlite3"},
+		{"plain.txt", []byte("just text\n"), ""},
+		{"empty", nil, ""},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, tt.content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := SniffMimeType(path)
+		if err != nil {
+			t.Errorf("SniffMimeType(%s): %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SniffMimeType(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := SniffMimeType(filepath.Join(dir, "missing")); err == nil {
+		t.Error("SniffMimeType of a missing file didn't fail")
+	}
+}
+
+func TestExplainExtensionMismatch(t *testing.T) {
+	tests := []struct {
+		path     string
+		detected string
+		flagged  bool
+	}{
+		{"/d/photo.pdf", "image/png", true},
+		{"/d/invoice.pdf", "application/vnd.microsoft.portable-executable", true},
+		{"/d/notes.txt", "application/gzip", true},
+		{"/d/report.docx", mimeZip, true}, // a docx without word/ is just a zip
+		{"/d/photo.PNG", "image/png", false},
+		{"/d/report.docx", mimeDocx, false},
+		{"/d/app.jar", mimeZip, false},
+		{"/d/libfoo.so.1", "application/x-elf", false}, // ".1" claims no type
+		{"/d/data.bak", "application/vnd.sqlite3", false},
+		{"/d/README", "application/pdf", false}, // no extension
+		{"/d/a.pdf", "", false},                 // not sniffed
+		{"/d/a.pdf", "text/x-unknown", false},   // not a type we know the extensions of
+	}
+	for _, tt := range tests {
+		info := &FileInfo{Path: tt.path, IsFile: true, DetectedMimeType: tt.detected, MimeType: "application/pdf"}
+		exp := ExplainExtensionMismatch(info)
+		if (exp != nil) != tt.flagged {
+			t.Errorf("%s as %q: flagged = %v, want %v", tt.path, tt.detected, exp != nil, tt.flagged)
+		}
+	}
+
+	exp := ExplainExtensionMismatch(&FileInfo{Path: "/d/photo.pdf", DetectedMimeType: "image/png", MimeType: "application/pdf"})
+	want := []string{
+		"Named .pdf, but the content is image/png",
+		"Expected extension: .png",
+		"Server reported: application/pdf",
+	}
+	if exp == nil || strings.Join(exp.Evidence, "\n") != strings.Join(want, "\n") {
+		t.Errorf("evidence = %q, want %q", exp, want)
+	}
+
+	if ExplainExtensionMismatch(&FileInfo{Path: "/d/x.pdf", IsDirectory: true, DetectedMimeType: "image/png"}) != nil {
+		t.Error("a directory was flagged")
+	}
+}
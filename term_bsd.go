@@ -0,0 +1,9 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package main
+
+import "syscall"
+
+const ioctlGetTermios = syscall.TIOCGETA
+
+const ioctlSetTermios = syscall.TIOCSETA
@@ -0,0 +1,15 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "os"
+
+// isTerminal falls back to "is a character device" where we don't know
+// the terminal ioctls
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
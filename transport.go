@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// Transport carries JSON-RPC messages between MCPClient and a server.
+// MCPClient only deals in whole messages; how they travel (pipes of a
+// child process, HTTP) is up to the transport.
+type Transport interface {
+	// Send delivers one JSON-encoded message to the server
+	Send(ctx context.Context, msg []byte) error
+
+	// Messages yields every message the server sends. It is closed when the
+	// connection ends, Err then says why.
+	Messages() <-chan []byte
+
+	// Err is why Messages was closed (nil for a clean end of stream)
+	Err() error
+
+	Close() error
+}
+
+// stdioTransport speaks newline-delimited JSON over a reader/writer pair,
+// normally the stdout/stdin of a child server process
+type stdioTransport struct {
+	w       io.Writer
+	writeMu sync.Mutex // one message line at a time
+
+	messages chan []byte
+	err      error
+}
+
+func newStdioTransport(r io.Reader, w io.Writer) *stdioTransport {
+	t := &stdioTransport{
+		w:        w,
+		messages: make(chan []byte, 16),
+	}
+	go t.readLoop(r)
+	return t
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || (line[0] != '{' && line[0] != '[') {
+			continue // log output on stdout, not JSON-RPC
+		}
+		t.messages <- append([]byte(nil), line...)
+	}
+
+	t.err = scanner.Err()
+	close(t.messages)
+}
+
+func (t *stdioTransport) Send(ctx context.Context, msg []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := t.w.Write(msg); err != nil {
+		return err
+	}
+	_, err := t.w.Write([]byte("\n"))
+	return err
+}
+
+func (t *stdioTransport) Messages() <-chan []byte { return t.messages }
+
+func (t *stdioTransport) Err() error { return t.err }
+
+// Close closes the server's stdin, which tells it we're done
+func (t *stdioTransport) Close() error {
+	if c, ok := t.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// httpTransport is the MCP Streamable HTTP transport: every message is
+// POSTed to one endpoint, the server answers with either a JSON body or an
+// SSE stream (text/event-stream) carrying one or more messages.
+//
+// The Mcp-Session-Id the server hands out with the initialize response is
+// sent back on every later request, and DELETEd on Close.
+type httpTransport struct {
+	url     string
+	headers map[string]string // extra headers, e.g. Authorization
+	client  *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+	closed          bool
+
+	messages  chan []byte
+	deliverMu sync.RWMutex  // Close waits for deliveries in progress
+	done      chan struct{} // closed by Close
+}
+
+func newHTTPTransport(url string, headers map[string]string, client *http.Client) *httpTransport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpTransport{
+		url:      url,
+		headers:  headers,
+		client:   client,
+		messages: make(chan []byte, 16),
+		done:     make(chan struct{}),
+	}
+}
+
+// setProtocolVersion is called by MCPClient once initialize negotiated a
+// version, later requests carry it in MCP-Protocol-Version
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	t.protocolVersion = version
+	t.mu.Unlock()
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *httpTransport) Send(ctx context.Context, msg []byte) error {
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return ErrClientClosed
+	}
+
+	req, err := t.newRequest(ctx, http.MethodPost, msg)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		// notifications and responses get no content back
+		resp.Body.Close()
+		return nil
+
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "":
+		resp.Body.Close()
+		return fmt.Errorf("MCP session expired (HTTP 404 from %s)", t.url)
+
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		defer resp.Body.Close()
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("MCP server returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		// the answer may take a while and come with notifications first,
+		// read it in the background so Send doesn't hold up the caller
+		go func() {
+			stop := make(chan struct{})
+			defer close(stop)
+			go func() {
+				select {
+				case <-t.done: // Close ends streams the server keeps open
+					resp.Body.Close()
+				case <-stop:
+				}
+			}()
+
+			defer resp.Body.Close()
+			t.readSSE(resp.Body)
+		}()
+		return nil
+
+	default:
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 {
+			t.deliver(body)
+		}
+		return nil
+	}
+}
+
+// readSSE passes the data of every "message" event on
+func (t *httpTransport) readSSE(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	event := ""
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" { // a blank line ends the event
+			if len(data) > 0 && (event == "" || event == "message") {
+				t.deliver([]byte(strings.Join(data, "\n")))
+			}
+			event, data = "", nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+		// "id", "retry" and ":" comments aren't needed
+	}
+	if len(data) > 0 && (event == "" || event == "message") {
+		t.deliver([]byte(strings.Join(data, "\n")))
+	}
+}
+
+func (t *httpTransport) deliver(msg []byte) {
+	t.deliverMu.RLock()
+	defer t.deliverMu.RUnlock()
+
+	select {
+	case <-t.done:
+		return // messages may already be closed
+	default:
+	}
+	select {
+	case t.messages <- msg:
+	case <-t.done:
+	}
+}
+
+func (t *httpTransport) Messages() <-chan []byte { return t.messages }
+
+// Err is always nil, HTTP failures are reported by Send
+func (t *httpTransport) Err() error { return nil }
+
+// Close ends the session on the server and stops delivering messages
+func (t *httpTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	var err error
+	if sessionID != "" {
+		req, reqErr := t.newRequest(context.Background(), http.MethodDelete, nil)
+		if reqErr == nil {
+			resp, doErr := t.client.Do(req)
+			if doErr == nil {
+				resp.Body.Close()
+			}
+			// 405 means the server doesn't let clients end sessions, fine
+			err = doErr
+		}
+	}
+
+	close(t.done)
+	t.deliverMu.Lock()
+	close(t.messages)
+	t.deliverMu.Unlock()
+	return err
+}
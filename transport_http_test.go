@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// httpRequest is what the stand-in server saw of one request
+type httpRequest struct {
+	method          string // HTTP method
+	rpcMethod       string
+	session         string
+	auth            string
+	protocolVersion string
+}
+
+// mcpHTTPServer is a minimal Streamable HTTP MCP server: initialize is
+// answered with JSON and hands out a session, tools/list with an SSE
+// stream, notifications with 202, and "expire" ends the session (404).
+type mcpHTTPServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []httpRequest
+	expired  bool
+}
+
+func newMCPHTTPServer(t *testing.T) *mcpHTTPServer {
+	s := &mcpHTTPServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *mcpHTTPServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var msg rpcMessage
+	json.Unmarshal(body, &msg)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, httpRequest{
+		method:          r.Method,
+		rpcMethod:       msg.Method,
+		session:         r.Header.Get("Mcp-Session-Id"),
+		auth:            r.Header.Get("Authorization"),
+		protocolVersion: r.Header.Get("MCP-Protocol-Version"),
+	})
+	expired := s.expired
+	if msg.Method == "expire" {
+		s.expired = true
+	}
+	s.mu.Unlock()
+
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if expired && r.Header.Get("Mcp-Session-Id") != "" {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	switch msg.Method {
+	case "initialize":
+		w.Header().Set("Mcp-Session-Id", "session-42")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-06-18","capabilities":{},"serverInfo":{"name":"stand-in","version":"1.0"}}}`, msg.ID)
+	case "tools/list":
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{}}\n\n")
+		fmt.Fprintf(w, "id: 1\ndata: {\"jsonrpc\":\"2.0\",\"id\":%s,\n", msg.ID)
+		fmt.Fprint(w, "data: \"result\":{\"tools\":[{\"name\":\"list_directory\"},{\"name\":\"get_file_info\"}]}}\n\n")
+	case "expire":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{}}`, msg.ID)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *mcpHTTPServer) seen() []httpRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]httpRequest(nil), s.requests...)
+}
+
+// newHTTPClient runs the handshake against the stand-in server
+func newHTTPClient(t *testing.T, s *mcpHTTPServer) *MCPClient {
+	t.Helper()
+	cfg := &ServerConfig{URL: s.URL, BearerToken: "s3cret", Headers: map[string]string{"X-Tenant": "docs"}}
+	client := newClient(newHTTPTransport(cfg.URL, cfg.httpHeaders(), s.Client()))
+	if err := client.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestHTTPTransportHandshake(t *testing.T) {
+	server := newMCPHTTPServer(t)
+	client := newHTTPClient(t, server)
+	defer client.Close()
+
+	// initialize came back as JSON, tools/list as an SSE stream
+	if client.Server.Name != "stand-in" || client.ProtocolVersion != "2025-06-18" {
+		t.Errorf("server %+v, protocol %s", client.Server, client.ProtocolVersion)
+	}
+	if err := client.RequireTools(requiredTools...); err != nil {
+		t.Error(err)
+	}
+
+	requests := server.seen()
+	var methods []string
+	for _, r := range requests {
+		methods = append(methods, r.rpcMethod)
+		if r.auth != "Bearer s3cret" {
+			t.Errorf("%s sent Authorization %q", r.rpcMethod, r.auth)
+		}
+	}
+	if got := strings.Join(methods, ","); got != "initialize,notifications/initialized,tools/list" {
+		t.Errorf("requests = %s", got)
+	}
+
+	if requests[0].session != "" {
+		t.Errorf("initialize sent session %q before getting one", requests[0].session)
+	}
+	for _, r := range requests[1:] {
+		if r.session != "session-42" {
+			t.Errorf("%s sent session %q, want session-42", r.rpcMethod, r.session)
+		}
+		if r.protocolVersion != "2025-06-18" {
+			t.Errorf("%s sent protocol version %q", r.rpcMethod, r.protocolVersion)
+		}
+	}
+}
+
+func TestHTTPTransportSessionExpired(t *testing.T) {
+	server := newMCPHTTPServer(t)
+	client := newHTTPClient(t, server)
+	defer client.Close()
+
+	if _, err := client.Call(context.Background(), "expire", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Call(context.Background(), "tools/list", nil)
+	if err == nil || !strings.Contains(err.Error(), "session expired") {
+		t.Errorf("error = %v, want session expired", err)
+	}
+}
+
+func TestHTTPTransportCloseDeletesSession(t *testing.T) {
+	server := newMCPHTTPServer(t)
+	client := newHTTPClient(t, server)
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	requests := server.seen()
+	last := requests[len(requests)-1]
+	if last.method != http.MethodDelete || last.session != "session-42" {
+		t.Errorf("last request = %s with session %q, want DELETE with session-42", last.method, last.session)
+	}
+
+	// closing twice doesn't send another DELETE
+	client.Close()
+	if n := len(server.seen()); n != len(requests) {
+		t.Errorf("second Close sent %d more requests", n-len(requests))
+	}
+}
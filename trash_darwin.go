@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// macTrash moves files into ~/.Trash like Finder does.
+// Finder keeps the original location in a private .DS_Store record,
+// so Finder's "Put Back" won't work for these, --undo does.
+type macTrash struct {
+	dir string
+}
+
+func newTrashBackend() TrashBackend {
+	homeDir, _ := os.UserHomeDir()
+	return macTrash{dir: filepath.Join(homeDir, ".Trash")}
+}
+
+func (macTrash) Name() string {
+	return "Trash"
+}
+
+func (t macTrash) Trash(path string) (string, error) {
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return "", err
+	}
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	// Finder style unique names: "report.pdf", "report 2.pdf", ...
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s %d%s", stem, n, ext)
+		}
+		dest := filepath.Join(t.dir, name)
+		if _, err := os.Lstat(dest); err == nil {
+			continue
+		}
+		if err := os.Rename(path, dest); err != nil {
+			return "", err
+		}
+		return dest, nil
+	}
+}
+
+func (macTrash) Restore(trashPath, originalPath string) error {
+	return os.Rename(trashPath, originalPath)
+}
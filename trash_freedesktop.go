@@ -0,0 +1,266 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// FreedesktopTrash implements the FreeDesktop.org Trash specification
+// (https://specifications.freedesktop.org/trash-spec/latest/), the trash
+// used by GNOME, KDE, XFCE and most Linux file managers.
+//
+// A trash directory has two subdirectories:
+//
+//	files/  the trashed files themselves
+//	info/   one <name>.trashinfo per file with its original path and deletion date
+//
+// Files on the same volume as the home directory go to the home trash
+// ($XDG_DATA_HOME/Trash). Files on other volumes go to a trash at the top of
+// that volume ($topdir/.Trash/$uid or $topdir/.Trash-$uid), because a rename
+// can't cross filesystems.
+type FreedesktopTrash struct {
+	// HomeTrash is the home trash directory, normally $XDG_DATA_HOME/Trash.
+	// Point it at a temp dir to test without touching the real trash.
+	HomeTrash string
+
+	// UID names the per-volume trash directories
+	UID int
+
+	// Now returns the deletion date written to .trashinfo files
+	Now func() time.Time
+}
+
+// NewFreedesktopTrash returns the trash of the current user
+func NewFreedesktopTrash() *FreedesktopTrash {
+	return &FreedesktopTrash{
+		HomeTrash: homeTrashDir(),
+		UID:       os.Getuid(),
+		Now:       time.Now,
+	}
+}
+
+// homeTrashDir returns $XDG_DATA_HOME/Trash, defaulting to ~/.local/share/Trash
+func homeTrashDir() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		homeDir, _ := os.UserHomeDir()
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash")
+}
+
+func (t *FreedesktopTrash) Name() string {
+	return "Trash"
+}
+
+// Trash moves path into the trash directory for its volume
+func (t *FreedesktopTrash) Trash(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	trashDir, topdir, err := t.trashDirFor(abs)
+	if err != nil {
+		return "", err
+	}
+
+	return t.moveInto(trashDir, topdir, abs)
+}
+
+// Restore moves trashPath (a file under <trash>/files) back to originalPath
+// and deletes its .trashinfo
+func (t *FreedesktopTrash) Restore(trashPath, originalPath string) error {
+	if err := os.Rename(trashPath, originalPath); err != nil {
+		return err
+	}
+
+	trashDir := filepath.Dir(filepath.Dir(trashPath))
+	infoPath := filepath.Join(trashDir, "info", filepath.Base(trashPath)+".trashinfo")
+	if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("restored, but could not remove %s: %v", infoPath, err)
+	}
+	return nil
+}
+
+// trashDirFor picks the trash directory for abs.
+// topdir is "" for the home trash (original paths are stored absolute),
+// otherwise the volume root the stored paths are relative to.
+func (t *FreedesktopTrash) trashDirFor(abs string) (trashDir, topdir string, err error) {
+	fileDev, err := deviceOf(abs)
+	if err != nil {
+		return "", "", err
+	}
+
+	homeDev, err := deviceOf(existingAncestor(t.HomeTrash))
+	if err == nil && homeDev == fileDev {
+		return t.HomeTrash, "", nil
+	}
+
+	topdir, err = volumeTopdir(abs, fileDev)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 1) $topdir/.Trash/$uid, only if the admin created .Trash as a
+	//    real directory with the sticky bit set
+	shared := filepath.Join(topdir, ".Trash")
+	if fi, err := os.Lstat(shared); err == nil &&
+		fi.IsDir() && fi.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, fmt.Sprint(t.UID))
+		if err := ensurePrivateDir(dir, t.UID); err == nil {
+			return dir, topdir, nil
+		}
+	}
+
+	// 2) $topdir/.Trash-$uid, created by us if needed
+	dir := filepath.Join(topdir, fmt.Sprintf(".Trash-%d", t.UID))
+	if err := ensurePrivateDir(dir, t.UID); err != nil {
+		return "", "", fmt.Errorf("no usable trash on the volume of %s: %v", abs, err)
+	}
+	return dir, topdir, nil
+}
+
+// moveInto writes the .trashinfo file and renames abs into trashDir/files.
+// The info file is created first with O_EXCL, which is how the spec
+// reserves a unique name. Returns the path of the file inside the trash.
+func (t *FreedesktopTrash) moveInto(trashDir, topdir, abs string) (string, error) {
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	storedPath := abs
+	if topdir != "" {
+		rel, err := filepath.Rel(topdir, abs)
+		if err != nil {
+			return "", err
+		}
+		storedPath = rel
+	}
+
+	now := time.Now
+	if t.Now != nil {
+		now = t.Now
+	}
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escapeTrashPath(storedPath),
+		now().Format("2006-01-02T15:04:05"))
+
+	base := filepath.Base(abs)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, n, ext)
+		}
+
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = f.WriteString(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", err
+		}
+
+		dest := filepath.Join(filesDir, name)
+		if err := os.Rename(abs, dest); err != nil {
+			os.Remove(infoPath)
+			return "", err
+		}
+		return dest, nil
+	}
+}
+
+// escapeTrashPath escapes the Path= value like a URL path,
+// keeping "/" as is, which is what the spec asks for
+func escapeTrashPath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// deviceOf returns the device id of the filesystem holding path.
+// Lstat so a trashed symlink is judged by where the link lives.
+func deviceOf(path string) (uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Dev), nil
+}
+
+// existingAncestor returns path or its closest parent that exists
+// (the home trash may not have been created yet)
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// volumeTopdir walks up from abs until the parent is on another device,
+// which gives the mount point of the volume holding abs
+func volumeTopdir(abs string, dev uint64) (string, error) {
+	dir := filepath.Dir(abs)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		parentDev, err := deviceOf(parent)
+		if err != nil {
+			return "", err
+		}
+		if parentDev != dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+// ensurePrivateDir creates dir with mode 0700 if it doesn't exist and checks
+// it's a real directory (not a symlink) owned by uid
+func ensurePrivateDir(dir string, uid int) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Lstat(dir, &st); err != nil {
+		return err
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if int(st.Uid) != uid {
+		return fmt.Errorf("%s is not owned by uid %d", dir, uid)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testTrash returns a trash in a temp dir, on the same volume as the files
+// the tests create so everything goes to the home trash
+func testTrash(t *testing.T) *FreedesktopTrash {
+	t.Helper()
+	return &FreedesktopTrash{
+		HomeTrash: filepath.Join(t.TempDir(), "Trash"),
+		UID:       os.Getuid(),
+		Now:       func() time.Time { return time.Date(2026, 1, 18, 15, 4, 5, 0, time.Local) },
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFreedesktopTrashWritesTrashInfo(t *testing.T) {
+	trash := testTrash(t)
+	src := filepath.Join(t.TempDir(), "my docs", "50% off #1.txt")
+	writeFile(t, src, "hello")
+
+	dest, err := trash.Trash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(trash.HomeTrash, "files", "50% off #1.txt"); dest != want {
+		t.Errorf("trashed to %s, want %s", dest, want)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("original still exists: %v", err)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "hello" {
+		t.Errorf("trashed content = %q, %v", data, err)
+	}
+
+	info, err := os.ReadFile(filepath.Join(trash.HomeTrash, "info", "50% off #1.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Trash Info]\nPath=" + escapeTrashPath(src) + "\nDeletionDate=2026-01-18T15:04:05\n"
+	if string(info) != want {
+		t.Errorf("trashinfo =\n%s\nwant\n%s", info, want)
+	}
+}
+
+func TestEscapeTrashPath(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/home/u/plain.txt", "/home/u/plain.txt"},
+		{"/home/u/my docs/a b.txt", "/home/u/my%20docs/a%20b.txt"},
+		{"/tmp/50% off.txt", "/tmp/50%25%20off.txt"},
+		{"/tmp/#1?.txt", "/tmp/%231%3F.txt"},
+		{"/tmp/übung.txt", "/tmp/%C3%BCbung.txt"},
+	}
+	for _, tt := range tests {
+		if got := escapeTrashPath(tt.in); got != tt.want {
+			t.Errorf("escapeTrashPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFreedesktopTrashUniqueNames(t *testing.T) {
+	trash := testTrash(t)
+	dir := t.TempDir()
+
+	var dests []string
+	for _, sub := range []string{"a", "b", "c"} {
+		src := filepath.Join(dir, sub, "report.pdf")
+		writeFile(t, src, sub)
+		dest, err := trash.Trash(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dests = append(dests, filepath.Base(dest))
+	}
+
+	want := []string{"report.pdf", "report.2.pdf", "report.3.pdf"}
+	for i := range want {
+		if dests[i] != want[i] {
+			t.Errorf("file %d trashed as %s, want %s", i, dests[i], want[i])
+		}
+		if _, err := os.Stat(filepath.Join(trash.HomeTrash, "info", want[i]+".trashinfo")); err != nil {
+			t.Errorf("no trashinfo for %s: %v", want[i], err)
+		}
+	}
+}
+
+func TestFreedesktopTrashRestore(t *testing.T) {
+	trash := testTrash(t)
+	src := filepath.Join(t.TempDir(), "notes.txt")
+	writeFile(t, src, "notes")
+
+	dest, err := trash.Trash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trash.Restore(dest, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(src); err != nil || string(data) != "notes" {
+		t.Errorf("restored content = %q, %v", data, err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("file still in the trash: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(trash.HomeTrash, "info", "notes.txt.trashinfo")); !os.IsNotExist(err) {
+		t.Errorf("trashinfo not removed: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package main
+
+func newTrashBackend() TrashBackend {
+	return NewFreedesktopTrash()
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
-// Colors for terminal output
-const (
+// Colors for terminal output.
+// Variables (not constants) so disableColors can switch them off
+var (
 	ColorReset  = "\033[0m"
 	ColorRed    = "\033[31m"
 	ColorYellow = "\033[33m"
@@ -17,6 +20,21 @@ const (
 	ColorDim    = "\033[2m"
 )
 
+// tuiOut receives the decorated human-readable output.
+// Machine-readable formats send it to io.Discard so only the report
+// ends up on stdout; --output points it at a file.
+var tuiOut io.Writer = os.Stdout
+
+// msgOut receives warnings, errors and prompts, which have to stay
+// visible whatever the output format is
+var msgOut io.Writer = os.Stdout
+
+// disableColors turns every Color* into an empty string
+func disableColors() {
+	ColorReset, ColorRed, ColorYellow, ColorGreen = "", "", "", ""
+	ColorBlue, ColorCyan, ColorBold, ColorDim = "", "", "", ""
+}
+
 // func PrintLogo() {
 //     // Replaced with a proper sitting cat ASCII
 //     logo := `
@@ -33,24 +51,24 @@ const (
 func PrintHeader(title string) {
 	width := 60
 	padding := (width - len(title)) / 2
-	fmt.Printf("\n%s%s%s\n",
+	fmt.Fprintf(tuiOut, "\n%s%s%s\n",
 		ColorCyan+ColorBold,
 		strings.Repeat("═", width),
 		ColorReset)
-	fmt.Printf("%s%s%s%s%s\n",
+	fmt.Fprintf(tuiOut, "%s%s%s%s%s\n",
 		ColorCyan+ColorBold,
 		strings.Repeat(" ", padding),
 		title,
 		strings.Repeat(" ", padding),
 		ColorReset)
-	fmt.Printf("%s%s%s\n\n",
+	fmt.Fprintf(tuiOut, "%s%s%s\n\n",
 		ColorCyan+ColorBold,
 		strings.Repeat("═", width),
 		ColorReset)
 }
 
 func PrintSection(title string) {
-	fmt.Printf("\n%s%s● %s%s\n",
+	fmt.Fprintf(tuiOut, "\n%s%s● %s%s\n",
 		ColorYellow+ColorBold,
 		strings.Repeat(" ", 2),
 		title,
@@ -58,7 +76,7 @@ func PrintSection(title string) {
 }
 
 func PrintFileInfo(label, value string) {
-	fmt.Printf("  %s%s%s: %s%s\n",
+	fmt.Fprintf(tuiOut, "  %s%s%s: %s%s\n",
 		ColorBlue,
 		strings.Repeat(" ", 4),
 		label,
@@ -67,7 +85,7 @@ func PrintFileInfo(label, value string) {
 }
 
 func PrintSuccess(message string) {
-	fmt.Printf("%s%s✓ %s%s\n",
+	fmt.Fprintf(tuiOut, "%s%s✓ %s%s\n",
 		ColorGreen,
 		strings.Repeat(" ", 4),
 		message,
@@ -75,7 +93,7 @@ func PrintSuccess(message string) {
 }
 
 func PrintWarning(message string) {
-	fmt.Printf("%s%s⚠ %s%s\n",
+	fmt.Fprintf(msgOut, "%s%s⚠ %s%s\n",
 		ColorYellow,
 		strings.Repeat(" ", 4),
 		message,
@@ -83,7 +101,7 @@ func PrintWarning(message string) {
 }
 
 func PrintError(message string) {
-	fmt.Printf("%s%s✗ %s%s\n",
+	fmt.Fprintf(msgOut, "%s%s✗ %s%s\n",
 		ColorRed,
 		strings.Repeat(" ", 4),
 		message,
@@ -91,67 +109,176 @@ func PrintError(message string) {
 }
 
 func PrintFileCount(count int) {
-	fmt.Printf("\n%s%s📁 Total Files Scanned: %d%s\n\n",
+	fmt.Fprintf(tuiOut, "\n%s%s📁 Total Files Scanned: %d%s\n\n",
 		ColorBold+ColorCyan,
 		strings.Repeat(" ", 2),
 		count,
 		ColorReset)
 }
 
-func PrintUnusedFile(path string, evidence []string) {
-	fmt.Printf("\n%s[UNUSED]%s %s\n",
-		ColorRed+ColorBold,
+// PrintParseWarnings sums up metadata the server sent that couldn't be
+// read: one line per field with how many entries were affected and the
+// first example, instead of one line per entry
+func PrintParseWarnings(files []*FileInfo) {
+	type fieldWarnings struct {
+		count   int
+		example string
+	}
+	byField := map[string]*fieldWarnings{}
+	var order []string
+
+	for _, info := range files {
+		for _, w := range info.ParseWarnings {
+			fw, ok := byField[w.Field]
+			if !ok {
+				fw = &fieldWarnings{example: info.Path + ": " + w.Error()}
+				byField[w.Field] = fw
+				order = append(order, w.Field)
+			}
+			fw.count++
+		}
+	}
+
+	for _, field := range order {
+		fw := byField[field]
+		PrintWarning(fmt.Sprintf("Could not read %q for %d entries, e.g. %s", field, fw.count, fw.example))
+	}
+}
+
+// severityColor picks the color findings of a severity are printed in
+func severityColor(sev Severity) string {
+	switch sev {
+	case SeverityCritical:
+		return ColorRed
+	case SeverityWarning:
+		return ColorYellow
+	default:
+		return ColorBlue
+	}
+}
+
+// PrintFinding prints one rule finding, e.g. "[ZERO-BYTE] path" with the
+// reason and evidence under it
+func PrintFinding(rule Rule, path string, exp *Explanation) {
+	color := severityColor(rule.Severity())
+	fmt.Fprintf(tuiOut, "\n%s[%s]%s %s\n",
+		color+ColorBold,
+		strings.ToUpper(rule.ID()),
 		ColorReset,
 		ColorBold+path)
-	for _, e := range evidence {
-		fmt.Printf("  %s%s▸%s %s\n",
-			ColorRed,
+	fmt.Fprintf(tuiOut, "  %sReason: %s%s\n",
+		color,
+		ColorReset,
+		exp.Reason)
+	for _, e := range exp.Evidence {
+		fmt.Fprintf(tuiOut, "  %s%s▸%s %s\n",
+			color,
 			strings.Repeat(" ", 2),
 			ColorReset,
 			e)
 	}
 }
 
-func PrintZeroByteFile(path, reason string, evidence []string) {
-	fmt.Printf("\n%s[ZERO-BYTE]%s %s\n",
-		ColorYellow+ColorBold,
-		ColorReset,
-		ColorBold+path)
-	fmt.Printf("  %sReason: %s%s\n",
-		ColorYellow,
-		ColorReset,
-		reason)
-	for _, e := range evidence {
-		fmt.Printf("  %s%s▸%s %s\n",
+// PrintDuplicateSets lists every set with its keeper marked
+func PrintDuplicateSets(sets []*DuplicateSet) {
+	PrintSection(fmt.Sprintf("Duplicate Sets (%d)", len(sets)))
+	for i, set := range sets {
+		fmt.Fprintf(tuiOut, "\n  %s%d.%s %d copies × %s, %s reclaimable\n",
 			ColorYellow,
-			strings.Repeat(" ", 2),
+			i+1,
 			ColorReset,
-			e)
+			len(set.Files),
+			formatFileSize(set.Size),
+			formatFileSize(set.Reclaimable()))
+		for _, f := range set.Files {
+			if f == set.Keeper {
+				fmt.Fprintf(tuiOut, "     %s[KEEP]%s %s\n", ColorGreen+ColorBold, ColorReset, f.Path)
+			} else {
+				fmt.Fprintf(tuiOut, "     %s[DUP] %s %s\n", ColorDim, ColorReset, f.Path)
+			}
+		}
+	}
+	fmt.Fprintf(tuiOut, "\n")
+}
+
+// PrintUsage prints the --usage tables, largest first
+func PrintUsage(u *UsageReport) {
+	PrintSection(fmt.Sprintf("Disk Usage: %s in %d files", formatFileSize(u.TotalBytes), u.TotalFiles))
+	printUsageTable("Largest Files", u.LargestFiles, u.TotalBytes, false)
+	printUsageTable("Largest Directories", u.LargestDirs, u.TotalBytes, true)
+	printUsageTable("By Type", u.ByType, u.TotalBytes, true)
+	fmt.Fprintf(tuiOut, "\n")
+}
+
+// printUsageTable prints size, share of total, file count and name columns
+func printUsageTable(title string, entries []UsageEntry, total int64, withFiles bool) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(tuiOut, "\n    %s%s%s\n", ColorBold, title, ColorReset)
+	for _, e := range entries {
+		share := 0.0
+		if total > 0 {
+			share = float64(e.Bytes) * 100 / float64(total)
+		}
+		files := ""
+		if withFiles {
+			files = fmt.Sprintf("%d files", e.Files)
+			if e.Files == 1 {
+				files = "1 file"
+			}
+		}
+		fmt.Fprintf(tuiOut, "    %10s %s%6.1f%%%s  %-10s %s\n",
+			formatFileSize(e.Bytes),
+			ColorDim,
+			share,
+			ColorReset,
+			files,
+			e.Name)
 	}
 }
 
 func PrintDivider() {
-	fmt.Printf("%s%s%s\n",
+	fmt.Fprintf(tuiOut, "%s%s%s\n",
 		ColorCyan,
 		strings.Repeat("─", 60),
 		ColorReset)
 }
 
-func PrintScanComplete(totalFiles, unusedCount, zeroByteCount int) {
-	fmt.Printf("\n")
+// PrintScanComplete prints the summary with one count per active rule and
+// the space the flagged files take
+func PrintScanComplete(rules []Rule, summary ReportSummary) {
+	fmt.Fprintf(tuiOut, "\n")
 	PrintDivider()
-	fmt.Printf("%sScan Summary:%s\n",
+	fmt.Fprintf(tuiOut, "%sScan Summary:%s\n",
 		ColorBold,
 		ColorReset)
-	PrintFileInfo("Files Scanned", fmt.Sprintf("%d", totalFiles))
-	PrintFileInfo("Unused Files", fmt.Sprintf("%d", unusedCount))
-	PrintFileInfo("Zero-Byte Files", fmt.Sprintf("%d", zeroByteCount))
+	PrintFileInfo("Files Scanned", fmt.Sprintf("%d", summary.FilesScanned))
+	for _, rule := range rules {
+		count := fmt.Sprintf("%d", summary.FindingsByRule[rule.ID()])
+		if size, ok := summary.ReclaimableBytesByRule[rule.ID()]; ok {
+			count += " (" + formatFileSize(size) + ")"
+		}
+		PrintFileInfo(ruleTitle(rule.ID())+" Files", count)
+	}
+	PrintFileInfo("Reclaimable", formatFileSize(summary.ReclaimableBytes))
 	PrintDivider()
-	fmt.Printf("\n")
+	fmt.Fprintf(tuiOut, "\n")
+}
+
+// ruleTitle turns a rule ID into a label: "zero-byte" -> "Zero-Byte"
+func ruleTitle(id string) string {
+	parts := strings.Split(id, "-")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "-")
 }
 
 func PrintInfo(message string) {
-	fmt.Printf("%s%sℹ %s%s\n",
+	fmt.Fprintf(tuiOut, "%s%sℹ %s%s\n",
 		ColorCyan,
 		strings.Repeat(" ", 4),
 		message,
@@ -171,21 +298,10 @@ func formatFileSize(size int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
-func getFileType(path string) string {
-	if strings.Contains(strings.ToLower(path), ".pdf") {
-		return "PDF"
-	}
-	if strings.Contains(strings.ToLower(path), ".doc") {
-		return "Document"
-	}
-	if strings.Contains(strings.ToLower(path), ".txt") {
-		return "Text"
-	}
-	if strings.Contains(strings.ToLower(path), ".jpg") || strings.Contains(strings.ToLower(path), ".png") {
-		return "Image"
-	}
-	if strings.Contains(strings.ToLower(path), ".mp4") || strings.Contains(strings.ToLower(path), ".avi") {
-		return "Video"
+// getFileType names the kind of file, see TypeCatalog.ClassifyFile
+func getFileType(info *FileInfo) string {
+	if cat := typeCatalog.ClassifyFile(info); cat != nil {
+		return cat.DisplayName()
 	}
 	return "Unknown"
 }
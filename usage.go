@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// defaultUsageTop is how many files and directories --usage lists
+const defaultUsageTop = 10
+
+// UsageEntry is one row of a usage table: a file, a directory with
+// everything below it, or a file type
+type UsageEntry struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+}
+
+// UsageReport is where the space of the matched files goes, every list
+// sorted largest first
+type UsageReport struct {
+	TotalBytes   int64        `json:"total_bytes"`
+	TotalFiles   int          `json:"total_files"`
+	LargestFiles []UsageEntry `json:"largest_files"`
+	LargestDirs  []UsageEntry `json:"largest_directories"`
+	ByType       []UsageEntry `json:"by_type"`
+}
+
+// BuildUsage adds up the sizes GetFileInfo collected. Every file counts
+// towards each directory above it up to its scan root, so a directory's
+// size includes its subdirectories (like du). top limits the file and
+// directory lists, 0 keeps everything; the scan roots themselves aren't
+// listed, their sum is TotalBytes.
+func BuildUsage(files []*FileInfo, top int) *UsageReport {
+	u := &UsageReport{}
+	dirs := map[string]*UsageEntry{}
+	types := map[string]*UsageEntry{}
+
+	for _, info := range files {
+		if info.IsDirectory {
+			continue
+		}
+		u.TotalBytes += info.SizeBytes
+		u.TotalFiles++
+		u.LargestFiles = append(u.LargestFiles, UsageEntry{Name: info.Path, Bytes: info.SizeBytes, Files: 1})
+
+		// RelPath "a/b/c.txt" has the directories a/b and a below the root
+		dir := info.Path
+		for i := strings.Count(info.RelPath, "/"); i > 0; i-- {
+			dir = parentPath(dir)
+			if dir == "" {
+				break
+			}
+			addUsage(dirs, dir, info.SizeBytes)
+		}
+
+		typeName := "Other"
+		if cat := typeCatalog.ClassifyFile(info); cat != nil {
+			typeName = cat.DisplayName()
+		}
+		addUsage(types, typeName, info.SizeBytes)
+	}
+
+	for _, e := range dirs {
+		u.LargestDirs = append(u.LargestDirs, *e)
+	}
+	for _, e := range types {
+		u.ByType = append(u.ByType, *e)
+	}
+
+	sortUsage(u.LargestFiles)
+	sortUsage(u.LargestDirs)
+	sortUsage(u.ByType)
+	if top > 0 {
+		if len(u.LargestFiles) > top {
+			u.LargestFiles = u.LargestFiles[:top]
+		}
+		if len(u.LargestDirs) > top {
+			u.LargestDirs = u.LargestDirs[:top]
+		}
+	}
+	return u
+}
+
+func addUsage(m map[string]*UsageEntry, name string, size int64) {
+	e, ok := m[name]
+	if !ok {
+		e = &UsageEntry{Name: name}
+		m[name] = e
+	}
+	e.Bytes += size
+	e.Files++
+}
+
+// sortUsage sorts largest first, by name for equal sizes so the output is
+// stable
+func sortUsage(entries []UsageEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Bytes != entries[j].Bytes {
+			return entries[i].Bytes > entries[j].Bytes
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
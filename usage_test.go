@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// usageFiles is a small scan of /r
+func usageFiles() []*FileInfo {
+	file := func(rel string, size int64) *FileInfo {
+		return &FileInfo{Path: "/r/" + rel, RelPath: rel, SizeBytes: size, IsFile: true}
+	}
+	return []*FileInfo{
+		file("a.pdf", 100),
+		{Path: "/r/docs", RelPath: "docs", IsDirectory: true, SizeBytes: 4096},
+		file("docs/b.pdf", 200),
+		file("docs/sub/c.png", 300),
+		file("docs/sub/d.bin", 50),
+		file("img/e.jpg", 400),
+	}
+}
+
+func usageRows(entries []UsageEntry) string {
+	var s string
+	for _, e := range entries {
+		s += fmt.Sprintf("%s=%d/%d ", e.Name, e.Bytes, e.Files)
+	}
+	return s
+}
+
+func TestBuildUsage(t *testing.T) {
+	u := BuildUsage(usageFiles(), 0)
+	if u.TotalBytes != 1050 || u.TotalFiles != 5 {
+		t.Errorf("total = %d bytes in %d files, want 1050 in 5 (directories don't count)", u.TotalBytes, u.TotalFiles)
+	}
+	// directories add up everything below them, the root isn't listed
+	if got, want := usageRows(u.LargestDirs), "/r/docs=550/3 /r/img=400/1 /r/docs/sub=350/2 "; got != want {
+		t.Errorf("directories = %s\nwant          %s", got, want)
+	}
+	if got, want := usageRows(u.ByType), "Image=700/2 PDF=300/2 Other=50/1 "; got != want {
+		t.Errorf("types = %s\nwant    %s", got, want)
+	}
+	if got, want := usageRows(u.LargestFiles), "/r/img/e.jpg=400/1 /r/docs/sub/c.png=300/1 /r/docs/b.pdf=200/1 /r/a.pdf=100/1 /r/docs/sub/d.bin=50/1 "; got != want {
+		t.Errorf("files = %s\nwant    %s", got, want)
+	}
+
+	// --top cuts the file and directory lists, not the types or totals
+	u = BuildUsage(usageFiles(), 2)
+	if len(u.LargestFiles) != 2 || len(u.LargestDirs) != 2 || len(u.ByType) != 3 || u.TotalBytes != 1050 {
+		t.Errorf("--top 2: %d files, %d dirs, %d types, %d bytes", len(u.LargestFiles), len(u.LargestDirs), len(u.ByType), u.TotalBytes)
+	}
+	if u.LargestDirs[1].Name != "/r/img" {
+		t.Errorf("--top 2 kept %s, want the largest", usageRows(u.LargestDirs))
+	}
+}
+
+func TestReportReclaimable(t *testing.T) {
+	unused, zero, mismatch := &UnusedRule{}, &ZeroByteRule{}, &ExtensionMismatchRule{}
+	rules := []Rule{unused, zero, &DuplicateRule{}, mismatch}
+	found := func(rules ...Rule) []RuleFinding {
+		var findings []RuleFinding
+		for _, r := range rules {
+			findings = append(findings, RuleFinding{Rule: r, Explanation: &Explanation{Reason: "test"}})
+		}
+		return findings
+	}
+
+	files := usageFiles()
+	report := &Report{}
+	report.AddFile(files[0], found(unused))           // 100
+	report.AddFile(files[2], found(unused, mismatch)) // 200, the mismatch doesn't count
+	report.AddFile(files[3], found(unused, zero))     // 300, counted once in the total
+	report.AddFile(files[5], found(mismatch))         // 400, not reclaimable at all
+
+	byRule, total := report.Reclaimable(rules)
+	if total != 600 {
+		t.Errorf("total = %d, want 600", total)
+	}
+	want := map[string]int64{"unused": 600, "zero-byte": 300, "duplicate": 0}
+	if fmt.Sprint(byRule) != fmt.Sprint(want) {
+		t.Errorf("by rule = %v, want %v", byRule, want)
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ScanError records a path the walk could not read.
+// The walk keeps going and these are reported at the end instead of aborting.
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+// ScanResult is everything a walk found under one root
+type ScanResult struct {
+	// Files holds every entry found (files AND directories) in the order the
+	// server listed them (depth-first). Files carry their metadata,
+	// directories only their path, the listing already says what they are.
+	Files []*FileInfo
+
+	// Errors holds directories/files that could not be read
+	Errors []ScanError
+
+	// Cycles holds directories that were skipped because their real path
+	// was already visited (symlink or junction pointing back up the tree)
+	Cycles []string
+
+	// Unfollowed holds symlinks to directories that weren't entered because
+	// they only exist on the server, where loops can't be detected
+	Unfollowed []string
+}
+
+// maxServerDepth caps the walk below directories that can't be resolved
+// locally. Cycle detection needs the real path, which only the server
+// knows, so a link loop the server doesn't report as a symlink would
+// otherwise go on forever (a/link/link/...).
+const maxServerDepth = 64
+
+// walker carries the state of one walk_directory call
+type walker struct {
+	client    *MCPClient
+	recursive bool
+	maxDepth  int
+	exclude   *PatternList
+	pool      *walkPool
+
+	mu      sync.Mutex
+	records []walkRecord
+	dirs    []walkedDir
+}
+
+// walkKey places what the walk found: the entry's index in every listing
+// on the way down from the root. Sorted by it, the results come out
+// depth-first in listing order, however the calls interleaved.
+type walkKey []int
+
+func (k walkKey) child(i int) walkKey { return append(k[:len(k):len(k)], i) }
+
+func (k walkKey) parent() walkKey { return k[:len(k)-1] }
+
+func (k walkKey) less(o walkKey) bool {
+	for i := 0; i < len(k) && i < len(o); i++ {
+		if k[i] != o[i] {
+			return k[i] < o[i]
+		}
+	}
+	return len(k) < len(o)
+}
+
+// within reports whether k is dir itself or below it
+func (k walkKey) within(dir walkKey) bool {
+	return len(k) >= len(dir) && slices.Equal(k[:len(dir)], dir)
+}
+
+type recordKind int
+
+const (
+	recordFile recordKind = iota
+	recordError
+	recordCycle
+	recordUnfollowed
+)
+
+// walkRecord is one thing for the ScanResult. owner is the listing it
+// came from, it is dropped when that listing turns out to be a repeat.
+type walkRecord struct {
+	key   walkKey
+	owner walkKey
+	kind  recordKind
+	info  *FileInfo // recordFile
+	err   error     // recordError
+	path  string
+}
+
+// walkedDir is a directory that was listed, and its real path
+type walkedDir struct {
+	key   walkKey
+	canon string
+	path  string
+}
+
+// dirTask is a directory to list. scopes and above are completed by its
+// listing before its entries are handed on.
+type dirTask struct {
+	path  string
+	rel   string
+	key   walkKey
+	depth int
+
+	scopes []ignoreScope // ignore files of the directory and its parents
+	above  []string      // real paths of the directory and its parents
+}
+
+// ignoreScope is an .analyzerignore and the directory it was found in,
+// relative to the scan root ("" for the root itself)
+type ignoreScope struct {
+	base string
+	list *PatternList
+}
+
+// walk_directory lists root through the MCP server and, when recursive is
+// set, descends into every subdirectory with repeated list_directory calls.
+//
+// maxDepth limits how many directory levels below root are visited:
+// 1 means only the entries of root itself, 0 means no limit.
+// Every file and symlink goes through GetFileInfo so callers get the same
+// metadata they would get from a flat listing. Up to workers calls (listings
+// and file infos, anywhere in the tree) are in flight at once; the result
+// order doesn't depend on it.
+//
+// Entries matching exclude or an .analyzerignore are skipped, excluded
+// directories aren't entered.
+func walk_directory(client *MCPClient, root string, recursive bool, maxDepth int, workers int, exclude *PatternList) *ScanResult {
+	w := &walker{
+		client:    client,
+		recursive: recursive,
+		maxDepth:  maxDepth,
+		exclude:   exclude,
+		pool:      newWalkPool(),
+	}
+	w.pool.submit(func() { w.list(&dirTask{path: root, key: walkKey{}, depth: 1}) })
+	w.pool.run(workers)
+	return w.result()
+}
+
+func (w *walker) add(r walkRecord) {
+	w.mu.Lock()
+	w.records = append(w.records, r)
+	w.mu.Unlock()
+}
+
+// realPath is the key for cycle detection. A remote server's paths can't
+// be resolved here, they are compared as they are.
+func (w *walker) realPath(dir string) string {
+	if w.client.Remote {
+		return filepath.Clean(dir)
+	}
+	return canonicalPath(dir)
+}
+
+// list lists dir and queues GetFileInfo for its entries. A directory that
+// is one of its own parents (a link back up the tree) is a cycle; one
+// reached twice elsewhere is sorted out by result.
+func (w *walker) list(dir *dirTask) {
+	canon := w.realPath(dir.path)
+	if slices.Contains(dir.above, canon) {
+		w.add(walkRecord{key: dir.key, owner: dir.key.parent(), kind: recordCycle, path: dir.path})
+		return
+	}
+	dir.above = append(dir.above[:len(dir.above):len(dir.above)], canon)
+	w.mu.Lock()
+	w.dirs = append(w.dirs, walkedDir{key: dir.key, canon: canon, path: dir.path})
+	w.mu.Unlock()
+
+	entries, err := list_directory(w.client, dir.path)
+	if err != nil {
+		w.add(walkRecord{key: dir.key, owner: dir.key, kind: recordError, err: err, path: dir.path})
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.Name != ignoreFileName || entry.Kind == EntryDir {
+			continue
+		}
+		lines, err := readIgnoreFile(w.client, entry.Path)
+		if err == nil {
+			var list *PatternList
+			if list, err = ParsePatterns(lines); err == nil {
+				dir.scopes = append(dir.scopes[:len(dir.scopes):len(dir.scopes)], ignoreScope{base: dir.rel, list: list})
+			}
+		}
+		if err != nil {
+			// before the entries' own errors
+			w.add(walkRecord{key: dir.key.child(-1), owner: dir.key, kind: recordError, err: err, path: entry.Path})
+		}
+	}
+
+	i := 0
+	for _, entry := range entries {
+		entryRel := joinRel(dir.rel, entry.Name)
+		if entry.Name == ignoreFileName || w.ignored(entryRel, entry.Kind == EntryDir, dir.scopes) {
+			continue
+		}
+		key := dir.key.child(i)
+		i++
+
+		// directories don't need GetFileInfo. Symlinks do, only the
+		// server knows what they point to.
+		if entry.Kind == EntryDir {
+			w.found(dir, entry, key, entryRel, &FileInfo{
+				Path:        entry.Path,
+				ListedPath:  entry.Path,
+				IsDirectory: true,
+				MimeType:    entry.MimeType,
+				Remote:      w.client.Remote,
+			}, nil)
+			continue
+		}
+		w.pool.submit(func() {
+			info, err := GetFileInfo(w.client, entry.Path)
+			if err == nil && info.MimeType == "" {
+				info.MimeType = entry.MimeType
+			}
+			w.found(dir, entry, key, entryRel, info, err)
+		})
+	}
+}
+
+// found records one entry of dir and queues the listing of a directory
+func (w *walker) found(dir *dirTask, entry DirEntry, key walkKey, rel string, info *FileInfo, err error) {
+	if err != nil {
+		w.add(walkRecord{key: key, owner: dir.key, kind: recordError, err: err, path: entry.Path})
+		return
+	}
+	info.RelPath = rel
+
+	// a symlink turned out to be a directory, directory-only patterns
+	// couldn't be checked before
+	if entry.Kind == EntrySymlink && info.IsDirectory && w.ignored(rel, true, dir.scopes) {
+		return
+	}
+	w.add(walkRecord{key: key, owner: dir.key, kind: recordFile, info: info})
+
+	if !info.IsDirectory || !w.recursive {
+		return
+	}
+	if w.maxDepth > 0 && dir.depth >= w.maxDepth {
+		return
+	}
+	if w.client.Remote || !resolvesLocally(info.Path) {
+		if entry.Kind == EntrySymlink {
+			w.add(walkRecord{key: key, owner: dir.key, kind: recordUnfollowed, path: info.Path})
+			return
+		}
+		if dir.depth >= maxServerDepth {
+			w.add(walkRecord{key: key, owner: dir.key, kind: recordError, path: info.Path,
+				err: fmt.Errorf("not entered, more than %d levels deep (use --max-depth)", maxServerDepth)})
+			return
+		}
+	}
+
+	sub := &dirTask{path: info.Path, rel: rel, key: key, depth: dir.depth + 1, scopes: dir.scopes, above: dir.above}
+	w.pool.submit(func() { w.list(sub) })
+}
+
+// result puts the records in depth-first order. A directory reached
+// through two links was listed twice; like a single worker would, the walk
+// keeps the first one in that order and reports the others as cycles.
+func (w *walker) result() *ScanResult {
+	sort.Slice(w.dirs, func(i, j int) bool { return w.dirs[i].key.less(w.dirs[j].key) })
+	visited := map[string]bool{}
+	var dropped []walkKey
+	isDropped := func(k walkKey) bool {
+		for _, d := range dropped {
+			if k.within(d) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, dir := range w.dirs {
+		if isDropped(dir.key) {
+			continue
+		}
+		if visited[dir.canon] {
+			dropped = append(dropped, dir.key)
+			w.records = append(w.records, walkRecord{key: dir.key, owner: dir.key.parent(), kind: recordCycle, path: dir.path})
+			continue
+		}
+		visited[dir.canon] = true
+	}
+
+	records := w.records[:0]
+	for _, r := range w.records {
+		if !isDropped(r.owner) {
+			records = append(records, r)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].key.less(records[j].key) })
+
+	result := &ScanResult{}
+	for _, r := range records {
+		switch r.kind {
+		case recordFile:
+			result.Files = append(result.Files, r.info)
+		case recordError:
+			result.Errors = append(result.Errors, ScanError{Path: r.path, Err: r.err})
+		case recordCycle:
+			result.Cycles = append(result.Cycles, r.path)
+		case recordUnfollowed:
+			result.Unfollowed = append(result.Unfollowed, r.path)
+		}
+	}
+	return result
+}
+
+// ignored asks --exclude, then every ignore file from the root down; the
+// last one with a matching pattern decides, so a deeper "!name" can take
+// back a shallower exclude
+func (w *walker) ignored(rel string, isDir bool, scopes []ignoreScope) bool {
+	ignored, _ := w.exclude.decide(rel, isDir)
+	for _, scope := range scopes {
+		sub := rel
+		if scope.base != "" {
+			sub = strings.TrimPrefix(rel, scope.base+"/")
+		}
+		if matched, decided := scope.list.decide(sub, isDir); decided {
+			ignored = matched
+		}
+	}
+	return ignored
+}
+
+func joinRel(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// readIgnoreFile reads an ignore file directly when it exists here, and
+// through the server's read tool otherwise (remote or sandboxed servers)
+func readIgnoreFile(client *MCPClient, path string) ([]string, error) {
+	err := fmt.Errorf("no read tool to fetch it from the server")
+	if !client.Remote {
+		var data []byte
+		if data, err = os.ReadFile(localPath(path)); err == nil {
+			return strings.Split(string(data), "\n"), nil
+		}
+	}
+
+	for _, tool := range []string{"read_text_file", "read_file"} {
+		if _, ok := client.Tools[tool]; !ok {
+			continue
+		}
+		raw, err := client.ToolCall(tool, map[string]any{"path": path})
+		if err != nil {
+			return nil, err
+		}
+		var resp MCPResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, err
+		}
+		if err := resp.Result.Err(); err != nil {
+			return nil, err
+		}
+		var lines []string
+		for _, item := range resp.Result.Content {
+			if item.Type == "text" {
+				lines = append(lines, strings.Split(item.Text, "\n")...)
+			}
+		}
+		return lines, nil
+	}
+	return nil, err
+}
+
+// walkPool runs the walk's tool calls on a fixed number of goroutines for
+// the whole tree. Jobs queue more jobs (a listing its entries), so the
+// queue has no bound, and the pool is done once it is empty and no job
+// is running.
+type walkPool struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []func()
+	running int
+}
+
+func newWalkPool() *walkPool {
+	p := &walkPool{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *walkPool) submit(job func()) {
+	p.mu.Lock()
+	p.queue = append(p.queue, job)
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// run works through the queue on n goroutines until it is done
+func (p *walkPool) run(n int) {
+	if n < 1 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				p.mu.Lock()
+				for len(p.queue) == 0 && p.running > 0 {
+					p.cond.Wait()
+				}
+				if len(p.queue) == 0 {
+					p.mu.Unlock()
+					return
+				}
+				job := p.queue[0]
+				p.queue = p.queue[1:]
+				p.running++
+				p.mu.Unlock()
+
+				job()
+
+				p.mu.Lock()
+				p.running--
+				done := p.running == 0 && len(p.queue) == 0
+				p.mu.Unlock()
+				if done {
+					p.cond.Broadcast()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// canonicalPath resolves symlinks and junctions so the same directory reached
+// through different links maps to one key.
+// Falls back to the cleaned path when it can't be resolved locally.
+func canonicalPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Clean(path)
+}
+
+// resolvesLocally reports whether canonicalPath can see through links in
+// path, i.e. it exists on this machine and not only on the server
+func resolvesLocally(path string) bool {
+	_, err := filepath.EvalSymlinks(path)
+	return err == nil
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newToolServer answers tools/call requests on a fake server with text
+// from tool, which gets the tool name and its path argument. An error
+// becomes an isError result. Calls are answered concurrently, like a real
+// server would, so tool must be safe for that.
+func newToolServer(t *testing.T, tool func(name, path string) (string, error)) *MCPClient {
+	t.Helper()
+	client, server := newFakeServer(t)
+	go func() {
+		for req := range server.requests {
+			if req.Method != "tools/call" {
+				continue
+			}
+			var params struct {
+				Name      string `json:"name"`
+				Arguments struct {
+					Path string `json:"path"`
+				} `json:"arguments"`
+			}
+			json.Unmarshal(req.Params, &params)
+			go func() {
+				out, err := tool(params.Name, params.Arguments.Path)
+				isError := err != nil
+				if isError {
+					out = err.Error()
+				}
+				text, _ := json.Marshal(out)
+				server.reply(req, fmt.Sprintf(`{"content":[{"type":"text","text":%s}],"isError":%v}`, text, isError))
+			}()
+		}
+	}()
+	return client
+}
+
+// remoteRoot doesn't exist on this machine, like a path on a remote server
+const remoteRoot = "/srv/analyzer-walk-test/remote"
+
+func TestWalkDoesNotFollowServerSymlinks(t *testing.T) {
+	// every directory has a symlink "loop" back to the root
+	client := newToolServer(t, func(name, path string) (string, error) {
+		switch name {
+		case "list_directory":
+			return "[FILE] a.txt\n[DIR] sub\n[SYMLINK] loop", nil
+		case "get_file_info":
+			if strings.HasSuffix(path, "/loop") {
+				return "size: 4096\nisDirectory: true\nisFile: false", nil
+			}
+			return "size: 10\nisDirectory: false\nisFile: true", nil
+		}
+		return "", nil
+	})
+
+	scan := walk_directory(client, remoteRoot, true, 0, 2, nil)
+	// sub also has a "sub", which has one, ... so the depth cap ends it
+	if len(scan.Unfollowed) != maxServerDepth {
+		t.Errorf("got %d unfollowed symlinks, want %d", len(scan.Unfollowed), maxServerDepth)
+	}
+	// depth first, so the root's own link comes last
+	if n := len(scan.Unfollowed); n > 0 && scan.Unfollowed[n-1] != remoteRoot+"/loop" {
+		t.Errorf("last unfollowed = %s, want %s/loop", scan.Unfollowed[n-1], remoteRoot)
+	}
+	for _, f := range scan.Files {
+		if strings.Contains(f.RelPath, "loop/") {
+			t.Fatalf("walked into the symlink: %s", f.RelPath)
+		}
+	}
+}
+
+func TestWalkCapsDepthOnServer(t *testing.T) {
+	// a loop the server reports as a plain directory
+	client := newToolServer(t, func(name, path string) (string, error) {
+		if name == "list_directory" {
+			return "[DIR] again", nil
+		}
+		return "", nil
+	})
+
+	scan := walk_directory(client, remoteRoot, true, 0, 2, nil)
+	if len(scan.Files) != maxServerDepth {
+		t.Errorf("got %d directories, want %d", len(scan.Files), maxServerDepth)
+	}
+	if len(scan.Errors) != 1 || !strings.Contains(scan.Errors[0].Err.Error(), "levels deep") {
+		t.Errorf("errors = %v, want one depth error", scan.Errors)
+	}
+
+	// --max-depth still wins when it is smaller
+	scan = walk_directory(client, remoteRoot, true, 3, 2, nil)
+	if len(scan.Files) != 3 || len(scan.Errors) != 0 {
+		t.Errorf("with --max-depth 3: %d entries, errors %v", len(scan.Files), scan.Errors)
+	}
+}
+
+func TestWalkRemoteUsesServerMetadata(t *testing.T) {
+	// the same paths exist here, with other content than on the server
+	dir := t.TempDir()
+	for _, name := range []string{"a.pdf", "b.pdf", "c.pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("%PDF-1.7 local copy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(sniff bool) { sniffContent = sniff }(sniffContent)
+	sniffContent = true
+
+	client := newToolServer(t, func(name, path string) (string, error) {
+		switch {
+		case name == "list_directory":
+			return "[FILE] a.pdf\n[FILE] b.pdf\n[FILE] c.pdf", nil
+		case strings.HasSuffix(path, "a.pdf"):
+			return "size: 0\nisDirectory: false\nisFile: true", nil
+		}
+		// b and c are as big as the local copies, which are identical
+		return "size: 19\nisDirectory: false\nisFile: true", nil
+	})
+	client.Remote = true
+
+	scan := walk_directory(client, dir, true, 0, 2, nil)
+	if len(scan.Files) != 3 {
+		t.Fatalf("got %d files, want 3 (errors %v)", len(scan.Files), scan.Errors)
+	}
+	a := scan.Files[0]
+	if !a.Remote || a.SizeBytes != 0 {
+		t.Errorf("size = %d (remote %v), want the server's 0", a.SizeBytes, a.Remote)
+	}
+	if !a.ChangedAt.IsZero() || a.DetectedMimeType != "" {
+		t.Errorf("read the local file: ctime %v, sniffed %q", a.ChangedAt, a.DetectedMimeType)
+	}
+	if ExplainZeroByte(a) == nil {
+		t.Error("the server's empty file isn't flagged")
+	}
+	if sets := FindDuplicates(scan.Files, KeepNewest); len(sets) != 0 {
+		t.Errorf("hashed the local files: %d duplicate sets", len(sets))
+	}
+	if err := DeleteFile(*a, &DeletionHistory{}, "s"); err == nil || !strings.Contains(err.Error(), "remote") {
+		t.Errorf("DeleteFile = %v, want a refusal", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.pdf")); err != nil {
+		t.Errorf("local file touched: %v", err)
+	}
+}
+
+// newLocalServer serves list_directory and get_file_info from the local
+// filesystem, like a stdio server would. refuse, if set, fails listing
+// the directories it returns true for.
+func newLocalServer(t *testing.T, refuse func(path string) bool) *MCPClient {
+	t.Helper()
+	return newToolServer(t, localTool(refuse))
+}
+
+func localTool(refuse func(path string) bool) func(name, path string) (string, error) {
+	return func(name, path string) (string, error) {
+		switch name {
+		case "list_directory":
+			if refuse != nil && refuse(path) {
+				return "", fmt.Errorf("Error: access denied: %s", path)
+			}
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return "", err
+			}
+			var lines []string
+			for _, e := range entries {
+				kind := "FILE"
+				switch {
+				case e.Type()&os.ModeSymlink != 0:
+					kind = "SYMLINK"
+				case e.IsDir():
+					kind = "DIR"
+				}
+				lines = append(lines, "["+kind+"] "+e.Name())
+			}
+			return strings.Join(lines, "\n"), nil
+		case "get_file_info":
+			st, err := os.Stat(path)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("size: %d\nmodified: %s\nisDirectory: %v\nisFile: %v",
+				st.Size(), st.ModTime().Format(time.RFC3339), st.IsDir(), st.Mode().IsRegular()), nil
+		}
+		return "", fmt.Errorf("unknown tool %s", name)
+	}
+}
+
+// mkTree creates files (and their directories) below dir; a name ending
+// in "/" is an empty directory
+func mkTree(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// relPaths lists the RelPath of every entry a walk found
+func relPaths(scan *ScanResult) []string {
+	var rels []string
+	for _, f := range scan.Files {
+		rels = append(rels, f.RelPath)
+	}
+	return rels
+}
+
+func TestWalkIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	mkTree(t, dir,
+		"a.log", "keep.txt", "notes.tmp",
+		"sub/b.log", "sub/c.tmp", "sub/important.tmp",
+		"sub/deep/d.tmp", "sub/deep/e.log",
+		"other/f.tmp",
+		"build/out.o",
+	)
+	// the root ignores *.tmp and build/ (--exclude keep.txt), sub takes
+	// one .tmp back and adds *.log below itself only
+	if err := os.WriteFile(filepath.Join(dir, ignoreFileName), []byte("*.tmp\nbuild/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", ignoreFileName), []byte("# sub\n!important.tmp\n*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// and deep takes back what sub excluded, but not what the root did
+	if err := os.WriteFile(filepath.Join(dir, "sub", "deep", ignoreFileName), []byte("!*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exclude, err := ParsePatterns([]string{"keep.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newLocalServer(t, nil)
+	scan := walk_directory(client, dir, true, 0, 2, exclude)
+	if len(scan.Errors) != 0 {
+		t.Fatalf("errors: %v", scan.Errors)
+	}
+	got := strings.Join(relPaths(scan), ",")
+	want := "a.log,other,sub,sub/deep,sub/deep/e.log,sub/important.tmp"
+	if got != want {
+		t.Errorf("walked %s\nwant   %s", got, want)
+	}
+}
+
+func TestWalkLocalDepth(t *testing.T) {
+	dir := t.TempDir()
+	mkTree(t, dir, "a.txt", "one/b.txt", "one/two/c.txt", "one/two/three/d.txt", "empty/")
+	client := newLocalServer(t, nil)
+
+	tests := []struct {
+		recursive bool
+		maxDepth  int
+		want      string
+	}{
+		{false, 0, "a.txt,empty,one"},
+		{true, 1, "a.txt,empty,one"},
+		{true, 2, "a.txt,empty,one,one/b.txt,one/two"},
+		{true, 0, "a.txt,empty,one,one/b.txt,one/two,one/two/c.txt,one/two/three,one/two/three/d.txt"},
+	}
+	for _, tt := range tests {
+		scan := walk_directory(client, dir, tt.recursive, tt.maxDepth, 2, nil)
+		if got := strings.Join(relPaths(scan), ","); got != tt.want {
+			t.Errorf("recursive=%v depth=%d: walked %s\nwant %s", tt.recursive, tt.maxDepth, got, tt.want)
+		}
+		if len(scan.Errors) != 0 {
+			t.Errorf("errors: %v", scan.Errors)
+		}
+	}
+
+	// files carry the server's metadata, directories only their path
+	scan := walk_directory(client, dir, true, 0, 2, nil)
+	for _, f := range scan.Files {
+		if !f.IsDirectory && f.SizeBytes != int64(len(f.RelPath)) {
+			t.Errorf("%s: size %d, want %d", f.RelPath, f.SizeBytes, len(f.RelPath))
+		}
+	}
+}
+
+func TestWalkDetectsSymlinkLoops(t *testing.T) {
+	dir := t.TempDir()
+	mkTree(t, dir, "a.txt", "sub/b.txt")
+	// sub/up points back at the root, sub/again at sub itself
+	if err := os.Symlink("..", filepath.Join(dir, "sub", "up")); err != nil {
+		t.Skipf("no symlinks here: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "again")); err != nil {
+		t.Fatal(err)
+	}
+	client := newLocalServer(t, nil)
+
+	scan := walk_directory(client, dir, true, 0, 2, nil)
+	if len(scan.Errors) != 0 {
+		t.Fatalf("errors: %v", scan.Errors)
+	}
+	// "again" is entered first (it sorts before sub), so sub is the repeat
+	want := []string{filepath.Join(dir, "again", "up"), filepath.Join(dir, "sub")}
+	if strings.Join(scan.Cycles, ",") != strings.Join(want, ",") {
+		t.Errorf("cycles = %v, want %v", scan.Cycles, want)
+	}
+	got := strings.Join(relPaths(scan), ",")
+	if want := "a.txt,again,again/b.txt,again/up,sub"; got != want {
+		t.Errorf("walked %s, want %s", got, want)
+	}
+}
+
+func TestWalkReportsUnreadableDirectory(t *testing.T) {
+	dir := t.TempDir()
+	mkTree(t, dir, "a/1.txt", "b/2.txt", "b/deeper/3.txt", "c/4.txt")
+	// stands in for a permission error (tests may run as root)
+	locked := filepath.Join(dir, "b")
+	client := newLocalServer(t, func(path string) bool { return path == locked })
+
+	scan := walk_directory(client, dir, true, 0, 2, nil)
+	if len(scan.Errors) != 1 || scan.Errors[0].Path != locked || !strings.Contains(scan.Errors[0].Err.Error(), "access denied") {
+		t.Errorf("errors = %v, want one for %s", scan.Errors, locked)
+	}
+	// the walk goes on with the directories after it
+	if got, want := strings.Join(relPaths(scan), ","), "a,a/1.txt,b,c,c/4.txt"; got != want {
+		t.Errorf("walked %s, want %s", got, want)
+	}
+}
+
+func TestWalkOrderWithWorkers(t *testing.T) {
+	// many small directories: one file each, so only a pool shared by the
+	// whole walk has calls from several directories in flight
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 4; j++ {
+			names = append(names, fmt.Sprintf("d%d/e%d/f.txt", i, j), fmt.Sprintf("d%d/e%d/g/h.txt", i, j))
+		}
+		names = append(names, fmt.Sprintf("d%d/x.txt", i))
+	}
+	mkTree(t, dir, names...)
+	locked := filepath.Join(dir, "d3", "e1")
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	tool := localTool(func(path string) bool { return path == locked })
+	client := newToolServer(t, func(name, path string) (string, error) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		// answers come back in a scrambled order
+		time.Sleep(time.Duration(rand.IntN(2000)) * time.Microsecond)
+		return tool(name, path)
+	})
+
+	describe := func(scan *ScanResult) string {
+		s := strings.Join(relPaths(scan), ",")
+		for _, e := range scan.Errors {
+			s += "; " + e.Path
+		}
+		return s
+	}
+	want := describe(walk_directory(client, dir, true, 0, 1, nil))
+	if !strings.HasPrefix(want, "d0,d0/e0,d0/e0/f.txt,d0/e0/g,d0/e0/g/h.txt,d0/e1,") || !strings.HasSuffix(want, "; "+locked) {
+		t.Fatalf("one worker walked %s", want)
+	}
+	mu.Lock()
+	maxInFlight = 0
+	mu.Unlock()
+
+	for run := 0; run < 5; run++ {
+		if got := describe(walk_directory(client, dir, true, 0, 8, nil)); got != want {
+			t.Fatalf("run %d with 8 workers:\n%s\nwant\n%s", run, got, want)
+		}
+	}
+	if maxInFlight < 2 {
+		t.Errorf("at most %d calls in flight with 8 workers", maxInFlight)
+	}
+}